@@ -0,0 +1,42 @@
+package depth
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// NewAdjacencyFormatter returns a Formatter writing a line for each unique package, sorted by
+// name, listing the packages it imports directly, such as "pkg: dep1 dep2". The lines are
+// simple to process with line based tools such as grep or awk.
+func NewAdjacencyFormatter() Formatter {
+	return FormatterFunc(writeAdjacency)
+}
+
+func writeAdjacency(w io.Writer, root *Pkg) error {
+	deps := map[string][]string{root.Name: nil}
+	for from, to := range root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		deps[from.Name] = append(deps[from.Name], to.Name)
+		if _, ok := deps[to.Name]; !ok {
+			deps[to.Name] = nil
+		}
+	}
+
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(deps)) {
+		slices.Sort(deps[name])
+		fmt.Fprintf(&b, "%s:", name)
+		for _, dep := range deps[name] {
+			fmt.Fprintf(&b, " %s", dep)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,20 @@
+package depth
+
+import "os"
+
+func ExampleNewAdjacencyFormatter() {
+	p := Pkg{Name: "root", Resolved: true, Deps: []Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "github.com/foo/bar", Resolved: true, Deps: []Pkg{
+			{Name: "strings", Internal: true, Resolved: true},
+			{Name: "errors", Internal: true, Resolved: true},
+		}},
+	}}
+
+	_ = NewAdjacencyFormatter().Format(os.Stdout, &p)
+	// Output:
+	// errors:
+	// github.com/foo/bar: errors strings
+	// root: github.com/foo/bar strings
+	// strings:
+}
@@ -16,7 +16,8 @@ func BenchmarkTree_ResolveStringsInternal(b *testing.B) {
 
 func BenchmarkTree_ResolveStringsTest(b *testing.B) {
 	benchmarkTreeResolveStrings(&Tree{
-		ResolveTest: true,
+		ResolveTest:  true,
+		ResolveXTest: true,
 	}, b)
 }
 
@@ -24,6 +25,7 @@ func BenchmarkTree_ResolveStringsInternalTest(b *testing.B) {
 	benchmarkTreeResolveStrings(&Tree{
 		ResolveInternal: true,
 		ResolveTest:     true,
+		ResolveXTest:    true,
 	}, b)
 }
 
@@ -34,3 +36,13 @@ func benchmarkTreeResolveStrings(t *Tree, b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkTree_ResolveNetHTTP(b *testing.B) {
+	var t Tree
+	for i := 0; i < b.N; i++ {
+		t.Reset()
+		if err := t.Resolve("net/http"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,17 +2,61 @@ package depth
 
 import (
 	"go/build"
+	"os"
+	"path/filepath"
 	"sync"
 )
 
+// CachingImporter is an Importer that caches the result of each import, so that packages
+// imported many times throughout a tree are only read once. It's safe for concurrent use,
+// and its zero value is ready to use.
 type CachingImporter struct {
-	mu    sync.Mutex
-	cache map[string]*build.Package
+	// Context is the build.Context used to import packages. If nil, build.Default is used.
+	Context *build.Context
+
+	mu     sync.Mutex
+	cache  map[cacheKey]cacheEntry
+	scopes map[string]string
+	stats  ImporterStats
+}
+
+// ImporterStats counts the imports made through a CachingImporter.
+type ImporterStats struct {
+	// Calls is the number of packages imported through the CachingImporter, of which Hits
+	// were found in the cache and Misses were imported with the build.Context.
+	Calls  int
+	Hits   int
+	Misses int
+}
+
+// HitRate returns the fraction of Calls that were Hits, or zero without any Calls.
+func (s ImporterStats) HitRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Calls)
+}
+
+// cacheKey identifies an import within the cache. The mode is included since a package
+// imported with build.FindOnly is missing the details of a full import. The source directory
+// only changes how relative and vendored imports are found, so dir is the source directory of
+// relative imports, and the vendor scope of the source directory of any other import. This
+// lets the many packages importing the same path share a single entry.
+type cacheKey struct {
+	path string
+	dir  string
+	mode build.ImportMode
+}
+
+// cacheEntry is the result of an import, which may have failed.
+type cacheEntry struct {
+	pkg *build.Package
+	err error
 }
 
 func NewCachingImporter() *CachingImporter {
 	return &CachingImporter{
-		cache: make(map[string]*build.Package),
+		cache: make(map[cacheKey]cacheEntry),
 	}
 }
 
@@ -20,15 +64,82 @@ func (c *CachingImporter) Import(path, srcDir string, mode build.ImportMode) (*b
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if pkg, ok := c.cache[path]; ok {
-		return pkg, nil
+	c.stats.Calls++
+	key := cacheKey{path, srcDir, mode}
+	if !build.IsLocalImport(path) {
+		key.dir = c.vendorScope(srcDir)
 	}
-	pkg, err := build.Default.Import(path, srcDir, mode)
-	if err == nil {
-		if existingPkg, ok := c.cache[path]; ok {
-			return existingPkg, nil
-		}
-		c.cache[path] = pkg
+	if entry, ok := c.cache[key]; ok {
+		c.stats.Hits++
+		return entry.pkg, entry.err
+	}
+	c.stats.Misses++
+	if c.cache == nil {
+		c.cache = make(map[cacheKey]cacheEntry)
+	}
+	ctx := c.Context
+	if ctx == nil {
+		ctx = &build.Default
+	}
+
+	// Failures are cached as well, so that a missing package referenced throughout the
+	// graph is only looked up once, unless they're transient and may succeed if retried.
+	pkg, err := ctx.Import(path, srcDir, mode)
+	if err == nil || !isTransient(err) {
+		c.cache[key] = cacheEntry{pkg, err}
 	}
 	return pkg, err
 }
+
+// vendorScope returns the deepest directory containing a vendor directory among srcDir and
+// its parents, or an empty string if there isn't one. Since the vendor directories searched
+// for an import are those of srcDir and its parents, every source directory with the same scope
+// finds the same package for a given import path. The scope of each directory is cached, and
+// the CachingImporter must be locked.
+func (c *CachingImporter) vendorScope(srcDir string) string {
+	if srcDir == "" {
+		return ""
+	}
+	if scope, ok := c.scopes[srcDir]; ok {
+		return scope
+	}
+	if c.scopes == nil {
+		c.scopes = make(map[string]string)
+	}
+
+	var scope string
+	if dir, err := filepath.Abs(srcDir); err == nil {
+		for {
+			if info, err := os.Stat(filepath.Join(dir, "vendor")); err == nil && info.IsDir() {
+				scope = dir
+				break
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	c.scopes[srcDir] = scope
+	return scope
+}
+
+// ClearCache removes all previously imported packages from the cache, so that they are
+// imported again on their next use.
+func (c *CachingImporter) ClearCache() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = make(map[cacheKey]cacheEntry)
+	c.scopes = nil
+}
+
+// Stats returns the counts of the imports made through the CachingImporter, which are not
+// reset by ClearCache.
+func (c *CachingImporter) Stats() ImporterStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
@@ -0,0 +1,106 @@
+package depth
+
+import (
+	"go/build"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingImporter_Import(t *testing.T) {
+	c := NewCachingImporter()
+
+	pkg, err := c.Import("strings", "", 0)
+	assert.NoError(t, err)
+	cached, err := c.Import("strings", "", 0)
+	assert.NoError(t, err)
+	assert.True(t, pkg == cached, "Expected the cached package to be returned")
+
+	// A FindOnly import doesn't share the cache entry of a full import.
+	found, err := c.Import("strings", "", build.FindOnly)
+	assert.NoError(t, err)
+	assert.False(t, pkg == found, "Expected a separate FindOnly import")
+}
+
+func TestCachingImporter_ImportError(t *testing.T) {
+	c := NewCachingImporter()
+
+	_, err := c.Import("notreal", "", 0)
+	assert.Error(t, err)
+	_, cachedErr := c.Import("notreal", "", 0)
+	assert.True(t, err == cachedErr, "Expected the cached error to be returned")
+
+	c.ClearCache()
+	_, clearedErr := c.Import("notreal", "", 0)
+	assert.Error(t, clearedErr)
+	assert.False(t, err == clearedErr, "Expected the import to be retried after clearing the cache")
+}
+
+func TestCachingImporter_Stats(t *testing.T) {
+	c := NewCachingImporter()
+	assert.Equal(t, ImporterStats{}, c.Stats())
+	assert.Equal(t, 0.0, c.Stats().HitRate())
+
+	for i := 0; i < 3; i++ {
+		_, _ = c.Import("strings", "", 0)
+	}
+	_, _ = c.Import("errors", "", 0)
+
+	s := c.Stats()
+	assert.Equal(t, ImporterStats{Calls: 4, Hits: 2, Misses: 2}, s)
+	assert.Equal(t, 0.5, s.HitRate())
+}
+
+func TestCachingImporter_ZeroValue(t *testing.T) {
+	var c CachingImporter
+
+	pkg, err := c.Import("strings", "", 0)
+	assert.NoError(t, err)
+	cached, err := c.Import("strings", "", 0)
+	assert.NoError(t, err)
+	assert.True(t, pkg == cached, "Expected the cached package to be returned")
+}
+
+func TestCachingImporter_ImportSharedBetweenDirs(t *testing.T) {
+	c := NewCachingImporter()
+
+	// Packages imported from different directories share an entry, unless they're relative.
+	pkg, err := c.Import("strings", "testdata/tree/a", 0)
+	assert.NoError(t, err)
+	cached, err := c.Import("strings", "testdata/tree/b", 0)
+	assert.NoError(t, err)
+	assert.True(t, pkg == cached, "Expected the cached package to be returned")
+
+	a, err := c.Import(".", "testdata/tree/a", 0)
+	assert.NoError(t, err)
+	b, err := c.Import(".", "testdata/tree/b", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", a.Name)
+	assert.Equal(t, "b", b.Name)
+}
+
+func TestCachingImporter_vendorScope(t *testing.T) {
+	var c CachingImporter
+	tree, err := filepath.Abs("testdata/tree")
+	assert.NoError(t, err)
+
+	assert.Equal(t, tree, c.vendorScope("testdata/tree/b/c"))
+	assert.Equal(t, tree, c.vendorScope("testdata/tree"))
+	assert.Equal(t, "", c.vendorScope("testdata/multipkg"))
+	assert.Equal(t, "", c.vendorScope(""))
+}
+
+func TestCachingImporter_Tree(t *testing.T) {
+	c := NewCachingImporter()
+	tr := Tree{ResolveInternal: true, Importer: c}
+	assert.NoError(t, tr.Resolve("net/http"))
+	before := c.Stats()
+
+	// The packages of another tree sharing the importer are found in the cache, rather than
+	// imported again from the directory of the package importing them in this tree. Only the
+	// few packages new to the tree are missed.
+	assert.NoError(t, tr.Clone().Resolve("net/http/httptest"))
+	s := c.Stats()
+	assert.Greater(t, s.Hits-before.Hits, (s.Calls-before.Calls)*9/10)
+}
@@ -1,14 +1,24 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"go/build"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/adapap/depth"
 )
@@ -18,24 +28,130 @@ const (
 	outputOpenPadding   = "  "
 	outputPrefix        = "├ "
 	outputPrefixLast    = "└ "
+
+	// jsonVersion is the version of the JSON envelope format.
+	jsonVersion = 1
+
+	highlightStart = "\033[1;33m"
+	highlightEnd   = "\033[0m"
+
+	// The colors of each kind of package, when the output is colored.
+	colorInternal   = "\033[36m"
+	colorExternal   = "\033[32m"
+	colorUnresolved = "\033[31m"
+	colorTest       = "\033[2m"
+	colorReset      = "\033[0m"
 )
 
-type summary struct {
-	numInternal int
-	numExternal int
-	numTesting  int
-	maxDepth    int
+// result holds the outcome of resolving a single root package.
+type result struct {
+	tree    *depth.Tree
+	elapsed time.Duration
+	err     error
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: depth [options] <packages>")
-		return
+// trimFlag is the flag.Value of -trim, which can be provided on its own to derive the
+// prefix from the root package, or with an explicit prefix.
+type trimFlag struct {
+	options *depth.Options
+}
+
+func (t trimFlag) String() string {
+	if t.options == nil {
+		return ""
 	}
+	return t.options.TrimPrefix
+}
+
+func (t trimFlag) Set(s string) error {
+	if enabled, err := strconv.ParseBool(s); err == nil {
+		t.options.TrimRootPrefix = enabled
+		return nil
+	}
+	t.options.TrimPrefix = s
+	return nil
+}
+
+func (t trimFlag) IsBoolFlag() bool {
+	return true
+}
+
+// jsonEnvelope is the versioned wrapper of the JSON output written with -envelope. The Root
+// is either the nested depth.Pkg, or a compactJSON graph.
+type jsonEnvelope struct {
+	Version int         `json:"version"`
+	Root    any         `json:"root"`
+	Stats   depth.Stats `json:"stats"`
+}
+
+// summaryJSON is the JSON output written with -summary-json, holding the Stats of a
+// single package.
+type summaryJSON struct {
+	Name string `json:"name"`
+	depth.Stats
+	Edges depth.EdgeStats `json:"edges"`
+}
+
+// compactJSON is the JSON output written with -compact, listing each unique package once
+// as a node, with each import as an edge between the indexes of two nodes.
+type compactJSON struct {
+	Nodes []compactNode `json:"nodes"`
+	Edges [][2]int      `json:"edges"`
+}
+
+// idJSON is the nested JSON output written with -json-ids, in which each Pkg has a stable ID
+// and references the ID of its parent. IDs are the indexes of the names of the packages among
+// the sorted, unique names in the tree, so they only change when the set of packages does.
+type idJSON struct {
+	depth.Pkg
 
+	ID       int  `json:"id"`
+	ParentID *int `json:"parentId,omitempty"`
+
+	// Deps shadows the dependencies of the Pkg, so that they include their IDs.
+	Deps []idJSON `json:"deps"`
+}
+
+// flatJSONPkg is an element of the JSON array written with -json-flat, describing a unique
+// package across every occurrence of it in the tree.
+type flatJSONPkg struct {
+	Name     string `json:"name"`
+	Internal bool   `json:"internal"`
+	// Test is true if the package is a test dependency anywhere in the tree.
+	Test       bool `json:"test"`
+	MinDepth   int  `json:"minDepth"`
+	ImportedBy int  `json:"importedBy"`
+}
+
+// compactNode is a node of the compactJSON output.
+type compactNode struct {
+	depth.Pkg
+
+	// Deps hides the dependencies of the Pkg, which are represented by edges instead.
+	Deps []depth.Pkg `json:"deps,omitempty"`
+}
+
+func main() {
 	t, options := parse(os.Args[1:])
-	if err := handlePkgs(t, options); err != nil {
-		return
+
+	// Without any package names, read them from stdin unless it's an interactive terminal.
+	if len(options.PackageNames) == 0 {
+		if isTerminal(os.Stdin) {
+			fmt.Println("Usage: depth [options] <packages>")
+			return
+		}
+
+		names, err := readPkgNames(os.Stdin)
+		if err != nil {
+			fmt.Printf("FATAL: unable to read packages from stdin: %v\n", err)
+			return
+		}
+		options.PackageNames = names
+	}
+
+	// Only failed checks of the dependencies exit non-zero, so they can be used as a build gate.
+	if err := handlePkgs(t, options); isCheckFailure(err) {
+		os.Exit(1)
 	}
 }
 
@@ -51,20 +167,145 @@ func parse(args []string) (*depth.Tree, *depth.Options) {
 
 	var includePattern string
 	var excludePattern string
+	var highlightPattern string
+	var countPrefix string
+	var banPattern string
+	var opaquePattern string
 	
 	// Import options.
 	f.BoolVar(&t.ResolveInternal, "internal", false, "If set, resolves dependencies of internal (stdlib) packages.")
-	f.BoolVar(&t.ResolveTest, "test", false, "If set, resolves dependencies used for testing.")
+	f.BoolVar(&t.HideInternalNoise, "no-noise", false, "If set, hides low-level stdlib packages such as unsafe and internal/abi from the output.")
+	f.BoolVar(&t.PruneNoise, "prune-noise", false, "If set, leaves low-level stdlib packages such as unsafe and internal/abi out of resolution, so they are neither resolved nor counted.")
+	f.BoolVar(&t.ResolveTest, "test", false, "If set, resolves dependencies used for testing, including those of external test packages unless -xtest=false.")
+	f.BoolVar(&t.MergeTestDeps, "merge-test", false, "If set, packages imported by both tests and non-test files are shown as non-test dependencies marked (also test).")
+	f.BoolVar(&t.ResolveXTest, "xtest", false, "If set, resolves dependencies used by external test packages (package foo_test).")
+	f.BoolVar(&t.SeparateTestRoots, "separate-xtest", false, "If set, shows the external test package of the root as its own dependency, named '<pkg> [test]'.")
 	f.IntVar(&t.MaxDepth, "max", 0, "Sets the maximum depth of dependencies to resolve.")
+	f.IntVar(&t.MaxPackages, "max-packages", 0, "Sets the maximum number of packages whose dependencies are resolved.")
+	f.IntVar(&t.ImportRetries, "retries", 0, "Sets the number of times imports failing with transient errors, such as network timeouts, are retried.")
+	f.IntVar(&t.MaxBreadth, "breadth", 0, "Sets the maximum number of dependencies shown for each package.")
+	f.BoolVar(&t.NoFollowSymlinks, "no-symlinks", false, "If set, doesn't resolve symlinks in package directories.")
+	f.BoolVar(&t.FindOnly, "findonly", false, "If set, only checks that the package and its direct dependencies exist, without resolving further.")
+	f.StringVar(&opaquePattern, "opaque", "", "If set, doesn't resolve the dependencies of packages matching the given pattern(s).")
+	f.BoolVar(&t.StopAtExternal, "stop-at-external", false, "If set, doesn't resolve the dependencies of packages outside of the standard library and the main module.")
+	f.DurationVar(&t.Timeout, "timeout", 0, "Sets the maximum time spent resolving, after which a partial tree is output.")
 	f.StringVar(&includePattern, "include", "", "If set, use the given pattern(s) as a prefix filter of package names to keep.")
 	f.StringVar(&excludePattern, "exclude", "", "If set, use the given pattern(s) as a prefix filter of package names to ignore.")
+	f.Var(&t.SortMode, "sort", "Sets the order of dependencies: internal (default), alpha, depth, or none.")
 	f.BoolVar(&t.Verbose, "verbose", false, "If set, print verbose output.")
+	f.BoolFunc("debug", "If set, logs each step of resolution to stderr.", func(string) error {
+		t.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		return nil
+	})
+	f.Func("tags", "If set, a comma or space-separated list of build tags to consider satisfied when resolving.", func(s string) error {
+		t.BuildTags = strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		return nil
+	})
+	f.StringVar(&t.GOROOT, "goroot", "", "If set, resolves the standard library of the Go toolchain installed in the directory provided.")
+	f.StringVar(&t.VendorDir, "vendor", "", "If set, resolves imports from the vendor directory provided before any other location.")
+	f.Func("allow", "If set, reads the external packages permitted as dependencies from a file, one pattern per line.", func(s string) error {
+		file, err := os.Open(s)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		t.Allowlist, err = readPkgNames(file)
+		return err
+	})
+	f.BoolVar(&options.Acyclic, "acyclic", false, "If set, fails if there are import cycles among the packages of the main module, listing them.")
+	f.BoolVar(&options.AcyclicAll, "acyclic-all", false, "If set, fails if there are import cycles among any packages, listing them.")
+	f.StringVar(&banPattern, "ban", "", "If set, fails if any of the given package pattern(s) are depended on, listing how each is imported.")
+	f.BoolFunc("mod", "If set, always resolves packages using modules.", func(string) error {
+		t.ModuleMode = depth.ModuleModeOn
+		return nil
+	})
+	f.BoolFunc("gopath", "If set, always resolves packages within the GOPATH rather than using modules.", func(string) error {
+		t.ModuleMode = depth.ModuleModeOff
+		return nil
+	})
+	f.Func("cgo", "If set, overrides whether cgo is enabled when resolving (true or false).", func(s string) error {
+		enabled, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		t.CgoEnabled = &enabled
+		return nil
+	})
 
 	// Output options.
-	f.BoolVar(&options.OutputJSON, "json", false, "If set, outputs the depencies in JSON format.")
+	f.StringVar(&options.Format, "format", "", "Sets the output format: tree (default), json, graphml, markdown, svg, dot or adjacency.")
+	f.BoolVar(&options.OutputJSON, "json", false, "If set, outputs the depencies in JSON format. Alias of -format json.")
+	f.BoolVar(&options.OutputGraphML, "graphml", false, "If set, outputs the dependencies as a GraphML document. Alias of -format graphml.")
+	f.BoolVar(&options.OutputSVG, "svg", false, "If set, outputs the dependencies as an SVG image. Alias of -format svg.")
+	f.BoolVar(&options.OutputAdjacency, "adjacency", false, "If set, outputs each unique package on a line, followed by its direct dependencies. Alias of -format adjacency.")
+	f.BoolVar(&options.OutputMarkdown, "markdown", false, "If set, outputs the dependencies as nested Markdown lists. Alias of -format markdown.")
+	f.BoolVar(&options.OutputDOT, "dot", false, "If set, outputs the dependencies as a Graphviz DOT digraph. Alias of -format dot.")
+	f.BoolVar(&options.FanIn, "fanin", false, "If set, includes the number of packages importing each package in the JSON output.")
+	f.BoolVar(&options.Height, "height", false, "If set, shows the number of levels of dependencies beneath each package in the tree and JSON output.")
+	f.IntVar(&options.MinHeight, "min-height", 0, "If set, only outputs packages with at least the given number of levels of dependencies beneath them.")
+	f.BoolVar(&options.MinDepth, "min-depth", false, "If set, includes the minimum depth at which each package appears in the JSON output.")
+	f.BoolVar(&options.JSONIDs, "json-ids", false, "If set, includes a stable integer ID for each package in the JSON output, with each dependency referencing the ID of its parent.")
+	f.BoolVar(&options.JSONFlat, "json-flat", false, "If set, outputs the JSON as a flat array of the unique packages, with their minimum depth and number of importers.")
+	f.BoolVar(&options.JSONCompact, "compact", false, "If set, outputs the JSON as a list of unique packages and the edges between them.")
+	f.BoolVar(&options.SummaryJSON, "summary-json", false, "If set, outputs only the summary stats of each package as a single line of JSON.")
+	f.BoolVar(&options.JSONEnvelope, "envelope", false, "If set, wraps the JSON output in a versioned object including summary stats.")
 	f.StringVar(&options.ExplainPkg, "explain", "", "If set, show which packages import the specified target")
+	f.StringVar(&options.SubtreePkg, "subtree", "", "If set, only outputs the dependencies of the specified package, wherever it is found in the tree")
+	f.StringVar(&options.CostPkg, "cost", "", "If set, show which packages are only imported through the specified target")
+	f.Func("path", "If set to 'from,to', shows the chains of imports from one package to another, shortest first.", func(s string) error {
+		from, to, ok := strings.Cut(s, ",")
+		if !ok || from == "" || to == "" {
+			return errors.New("expected two packages separated by a comma")
+		}
+		options.PathFrom, options.PathTo = strings.TrimSpace(from), strings.TrimSpace(to)
+		return nil
+	})
+	f.IntVar(&t.MaxPaths, "max-paths", 0, "Sets the maximum number of paths shown by -path (default 100).")
+	f.BoolVar(&options.ShowPositions, "positions", false, "If set, shows the file:line positions of each import in the tree and explain output.")
+	f.BoolVar(&options.OnlyUnresolved, "only-unresolved", false, "If set, only outputs unresolved packages and the packages importing them.")
+	f.BoolVar(&options.OnlyTest, "only-test", false, "If set, only outputs test dependencies and the packages importing them.")
+	f.BoolVar(&options.CollapseStdlibInternal, "collapse-internal", false, "If set, shows the internal packages of the standard library imported by each package as a single '(stdlib internals)' package.")
+	f.BoolVar(&options.ListStdlib, "stdlib", false, "If set, only lists the standard library packages depended on.")
+	f.BoolVar(&options.TopoSort, "topo", false, "If set, lists the packages in topological order, with dependencies before the packages importing them.")
+	f.BoolVar(&options.Direct, "direct", false, "If set, only lists the packages imported directly, without resolving their dependencies.")
+	f.BoolVar(&options.ListTestOnly, "test-only", false, "If set, only lists the packages depended on solely because of tests, when resolved with -test or -xtest.")
+	f.BoolVar(&options.ListLeaves, "leaves", false, "If set, only lists the packages without dependencies of their own.")
+	f.IntVar(&options.PreviewMaxDepth, "preview-max", 0, "If set, only lists the packages that would be left out with -max set to the given depth.")
+	f.BoolVar(&options.Histogram, "histogram", false, "If set, outputs a chart of the number of unique packages at each depth.")
+	f.BoolVar(&options.MinGoVersion, "gover", false, "If set, only outputs the minimum Go version required by the modules depended on.")
+	f.BoolVar(&options.Metrics, "metrics", false, "If set, only outputs metrics of the dependency graph, such as its number of edges and density.")
+	f.BoolVar(&options.ListFlat, "flat", false, "If set, only lists the unique packages depended on, sorted by name.")
+	f.StringVar(&options.BaselineFile, "baseline", "", "If set, fails if any package depended on is missing from the file provided, as written by -flat.")
+	f.BoolVar(&options.UpdateBaseline, "update-baseline", false, "If set, writes the packages depended on to the -baseline file rather than checking them.")
+	f.BoolVar(&options.ListConflicts, "conflicts", false, "If set, only lists the modules required at more than one version, along with the versions.")
+	f.BoolVar(&options.ListLOC, "loc", false, "If set, lists the lines of code of each package, from largest to smallest.")
+	f.BoolVar(&t.IgnoreVendor, "ignore-vendor", false, "If set, doesn't count the lines of code of vendored packages with -loc.")
+	f.BoolVar(&options.Quiet, "quiet", false, "If set, suppresses the summary and timing lines.")
+	f.StringVar(&countPrefix, "count-prefix", "", "If set, adds a summary line counting the packages with each of the given prefix(es).")
+	f.BoolVar(&options.CountExternal, "count-external", false, "If set, adds a summary line counting only third-party packages.")
+	f.Var(trimFlag{&options}, "trim", "If set, shows package names relative to the root's module, or to the prefix provided with -trim=<prefix>.")
+	f.StringVar(&options.RootLabel, "root-label", "", "If set, shows the root package with the given label rather than its name in the output.")
+	f.StringVar(&options.Template, "template", "", "If set, prints each package with the text/template provided, such as '{{.Name}} ({{.Depth}})'.")
+	f.Var(&options.Color, "color", "Sets when the tree output is colored: auto (default, when writing to a terminal), always, or never.")
+	f.StringVar(&highlightPattern, "highlight", "", "If set, highlight package names matching the given pattern(s) in the tree output.")
+
+	// Execution options.
+	f.BoolVar(&options.Recursive, "recursive", false, "If set, resolves every package within each directory provided, and those beneath it, as a single tree.")
+	f.BoolVar(&options.Watch, "watch", false, "If set, shows the number of packages resolved so far on stderr while resolving, except with -parallel.")
+	f.BoolVar(&options.WatchFiles, "watch-files", false, "If set, resolves the packages again each time the Go files of any of their dependencies change.")
+	f.BoolVar(&options.Parallel, "parallel", false, "If set, resolves multiple packages concurrently.")
+	f.IntVar(&options.MaxConcurrency, "concurrency", runtime.NumCPU(), "Sets the maximum number of packages resolved at once with -parallel.")
 
 	_ = f.Parse(args)
+
+	// -test covers every test dependency, unless -xtest is given to control the external
+	// test package separately.
+	if t.ResolveTest && !isFlagSet(f, "xtest") {
+		t.ResolveXTest = true
+	}
 	
 	if includePattern != "" {
 		t.IncludePatterns = strings.Split(includePattern, ",")
@@ -72,96 +313,706 @@ func parse(args []string) (*depth.Tree, *depth.Options) {
 	if excludePattern != "" {
 		t.ExcludePatterns = strings.Split(excludePattern, ",")
 	}
+	if highlightPattern != "" {
+		options.HighlightPatterns = strings.Split(highlightPattern, ",")
+	}
+	if opaquePattern != "" {
+		t.OpaquePackages = strings.Split(opaquePattern, ",")
+	}
+	if banPattern != "" {
+		t.Banned = strings.Split(banPattern, ",")
+	}
+	if countPrefix != "" {
+		options.CountPrefixes = strings.Split(countPrefix, ",")
+	}
+	if options.ListLOC {
+		t.CountLOC = true
+	}
 
 	options.PackageNames = f.Args()
 
 	return t, &options
 }
 
+// readPkgNames reads newline-separated package names from r, ignoring blank lines and
+// lines starting with #.
+func readPkgNames(r io.Reader) ([]string, error) {
+	var names []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, s.Err()
+}
+
 // handlePkgs takes a slice of package names, resolves a Tree on them,
 // and outputs each Tree to Stdout.
 func handlePkgs(t *depth.Tree, options *depth.Options) error {
+	if _, err := newFormatter(options); err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return err
+	}
+	// Packages resolved in parallel would each overwrite the line showing progress, and
+	// watching files resolves them one at a time, so -parallel would be ignored.
+	if options.Parallel && (options.Watch || options.WatchFiles) {
+		err := errors.New("-watch and -watch-files can't be used with -parallel")
+		fmt.Printf("FATAL: %v\n", err)
+		return err
+	}
+
+	if t.Verbose {
+		defer writeImporterStats(os.Stderr, t)
+	}
+
+	// Each directory is resolved as a whole, so patterns are kept rather than expanded.
+	if options.Recursive {
+		var failed error
+		for _, pkg := range options.PackageNames {
+			r := resolveWithProgress(t, pkg, options, func() error {
+				return t.ResolveDir(strings.TrimSuffix(pkg, "/..."))
+			})
+			if err := writeResult(os.Stdout, pkg, r, options); err != nil && !isCheckFailure(err) {
+				return err
+			} else if failed == nil {
+				failed = err
+			}
+		}
+		return failed
+	}
+
+	names, err := depth.ExpandPatterns(t.BuildContext(), options.PackageNames)
+	if err != nil {
+		fmt.Printf("FATAL: %v\n", err)
+		return err
+	}
+	options.PackageNames = names
+
+	if options.Direct {
+		return writeDirect(os.Stdout, t, options.PackageNames)
+	}
+	if options.WatchFiles {
+		return watchPkgs(t, options)
+	}
+	if options.Parallel {
+		return handlePkgsParallel(t, options)
+	}
+
+	// Packages failing a check are still followed by the rest, so every failure is shown.
+	var failed error
 	for _, pkg := range options.PackageNames {
+		if err := writeResult(os.Stdout, pkg, resolvePkg(t, pkg, options), options); err != nil && !isCheckFailure(err) {
+			return err
+		} else if failed == nil {
+			failed = err
+		}
+	}
+	return failed
+}
 
-		start := time.Now()
-		err := t.Resolve(pkg)
+// writeDirect writes the packages imported directly by each of the packages named, beneath
+// a header for each when there are several.
+func writeDirect(w io.Writer, t *depth.Tree, pkgs []string) error {
+	for _, pkg := range pkgs {
+		deps, err := t.ResolveDirect(pkg)
 		if err != nil {
-			fmt.Printf("'%v': FATAL: %v\n", pkg, err)
+			fmt.Fprintf(w, "'%v': FATAL: %v\n", pkg, err)
 			return err
 		}
-		elapsed := time.Since(start)
 
-		if options.OutputJSON {
-			if err := writePkgJSON(os.Stdout, *t.Root); err != nil {
-				return err
+		var padding string
+		if len(pkgs) > 1 {
+			fmt.Fprintf(w, "%s:\n", pkg)
+			padding = outputClosedPadding
+		}
+		for _, dep := range deps {
+			fmt.Fprintf(w, "%s%s\n", padding, dep)
+		}
+	}
+	return nil
+}
+
+// isFlagSet returns true if the flag named was set on the command line.
+func isFlagSet(f *flag.FlagSet, name string) bool {
+	var set bool
+	f.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// resolvePkg resolves the package named on the Tree, reporting its progress if requested
+// by the Options.
+func resolvePkg(t *depth.Tree, pkg string, options *depth.Options) result {
+	return resolveWithProgress(t, pkg, options, func() error {
+		return t.Resolve(pkg)
+	})
+}
+
+// resolveWithProgress calls resolve to resolve the package named on the Tree, reporting its
+// progress if requested by the Options, and returns the result.
+func resolveWithProgress(t *depth.Tree, pkg string, options *depth.Options, resolve func() error) result {
+	var p *progress
+	if options.Watch {
+		p = startProgress(os.Stderr, isTerminal(os.Stderr), pkg)
+		t.Progress = p.update
+	}
+	start := time.Now()
+	err := resolve()
+	if p != nil {
+		p.stop()
+		t.Progress = nil
+	}
+	return result{t, time.Since(start), err}
+}
+
+// handlePkgsParallel resolves each package name on its own clone of the Tree using
+// a bounded pool of workers, and outputs the results in the order they were provided.
+func handlePkgsParallel(t *depth.Tree, options *depth.Options) error {
+	// Share a single importer between the clones so its cache is reused.
+	if t.Importer == nil {
+		importer := depth.NewCachingImporter()
+		importer.Context = t.BuildContext()
+		t.Importer = importer
+	}
+
+	workers := options.MaxConcurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]result, len(options.PackageNames))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				tree := t.Clone()
+				start := time.Now()
+				err := tree.Resolve(options.PackageNames[idx])
+				results[idx] = result{tree, time.Since(start), err}
 			}
-			continue
+		}()
+	}
+	for idx := range options.PackageNames {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed error
+	for idx, pkg := range options.PackageNames {
+		if err := writeResult(os.Stdout, pkg, results[idx], options); err != nil && !isCheckFailure(err) {
+			return err
+		} else if failed == nil {
+			failed = err
 		}
+	}
+	return failed
+}
 
-		if options.ExplainPkg != "" {
-			writeExplain(os.Stdout, *t.Root, []string{}, options.ExplainPkg)
-			continue
+// describeErr returns a description of the error encountered resolving the package named,
+// explaining why the root package couldn't be imported when the cause is known.
+func describeErr(pkg string, r result) string {
+	if !errors.Is(r.err, depth.ErrRootPkgNotResolved) || r.tree.Root == nil || r.tree.Root.Err == nil {
+		return r.err.Error()
+	}
+
+	cause := r.tree.Root.Err
+	var multiErr *build.MultiplePackageError
+	switch {
+	case errors.As(cause, &multiErr):
+		return fmt.Sprintf("%v: %v, a directory may only contain a single package", r.err, multiErr)
+	case isNotFound(cause) && build.IsLocalImport(pkg):
+		return fmt.Sprintf("%v: no package found in the directory, check that the path is correct", r.err)
+	case isNotFound(cause):
+		return fmt.Sprintf("%v: package not found, check the import path or run 'go get' to add its module", r.err)
+	default:
+		// Only the first line is shown, as the rest tends to list every directory searched.
+		msg, _, _ := strings.Cut(cause.Error(), "\n")
+		return fmt.Sprintf("%v: %v", r.err, msg)
+	}
+}
+
+// isNotFound returns true if the error returned by build.Import indicates that no package
+// exists with the import path, which isn't distinguished by its type.
+func isNotFound(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "cannot find package") ||
+		strings.Contains(msg, "is not in std") ||
+		strings.Contains(msg, "no required module provides package")
+}
+
+// errPolicyViolation is returned when a resolved Tree depends on packages its policy does
+// not permit.
+var errPolicyViolation = errors.New("dependency policy violated")
+
+// checkPolicy writes each dependency of the Tree that is not permitted by its Allowlist,
+// and the import paths of each of its Banned dependencies, returning errPolicyViolation if
+// there are any.
+func checkPolicy(w io.Writer, pkg string, t *depth.Tree) error {
+	violations := t.Violations()
+	for _, name := range violations {
+		fmt.Fprintf(w, "'%v': %s is not in the allowlist\n", pkg, name)
+	}
+
+	banned := t.BannedFound()
+	names := slices.Sorted(maps.Keys(banned))
+	for _, name := range names {
+		fmt.Fprintf(w, "'%v': %s is banned, imported by:\n", pkg, name)
+		for _, path := range banned[name] {
+			fmt.Fprintf(w, "%s%s\n", outputClosedPadding, path)
 		}
+	}
 
-		writePkg(os.Stdout, *t.Root)
-		writePkgSummary(os.Stdout, *t.Root)
-		fmt.Printf("Resolved <%s> in %s\n", pkg, elapsed)
+	if len(violations) > 0 || len(banned) > 0 {
+		return errPolicyViolation
 	}
 	return nil
 }
 
-// writePkgSummary writes a summary of all packages in a tree
-func writePkgSummary(w io.Writer, pkg depth.Pkg) {
-	var sum summary
-	set := make(map[string]struct{})
-	for _, p := range pkg.Deps {
-		collectSummary(&sum, p, set)
-	}
-	fmt.Fprintf(w, "%d dependencies (%d internal, %d external, %d testing) | max depth: %d\n",
-		sum.numInternal+sum.numExternal,
-		sum.numInternal,
-		sum.numExternal,
-		sum.numTesting,
-		sum.maxDepth)
-}
-
-func collectSummary(sum *summary, pkg depth.Pkg, nameSet map[string]struct{}) {
-	if _, ok := nameSet[pkg.Name]; !ok {
-		nameSet[pkg.Name] = struct{}{}
-		if pkg.Internal {
-			sum.numInternal++
+// checkAcyclic writes each import cycle of the Tree, only among the packages of its main
+// module if firstParty is true, returning depth.ErrImportCycle if there are any.
+func checkAcyclic(w io.Writer, pkg string, t *depth.Tree, firstParty bool) error {
+	cycles := t.ImportCycles(firstParty)
+	for _, cycle := range cycles {
+		fmt.Fprintf(w, "'%v': %v: %s\n", pkg, depth.ErrImportCycle, strings.Join(cycle, " -> "))
+	}
+
+	if len(cycles) > 0 {
+		return depth.ErrImportCycle
+	}
+	return nil
+}
+
+// writeImporterStats writes the counts of the imports made by the Tree, if its Importer is
+// a CachingImporter.
+func writeImporterStats(w io.Writer, t *depth.Tree) {
+	importer, ok := t.Importer.(*depth.CachingImporter)
+	if !ok {
+		return
+	}
+
+	s := importer.Stats()
+	fmt.Fprintf(w, "%d import calls, %d cache hits, %d misses (%.0f%% hit rate)\n",
+		s.Calls,
+		s.Hits,
+		s.Misses,
+		s.HitRate()*100)
+}
+
+// isCheckFailure returns true if the error is the failure of a check of the dependencies, such
+// as -allow, -ban, -acyclic or -baseline, rather than an error resolving or writing them.
+func isCheckFailure(err error) bool {
+	return errors.Is(err, errPolicyViolation) || errors.Is(err, depth.ErrImportCycle) || errors.Is(err, errBaselineGrowth)
+}
+
+// writeResult outputs the resolved Tree of a single package, or the error encountered
+// while resolving it. The result is written even if it fails the checks selected by the
+// Options, after which the failure is returned.
+func writeResult(w io.Writer, pkg string, r result, options *depth.Options) error {
+	if errors.Is(r.err, depth.ErrTimeout) || errors.Is(r.err, depth.ErrMaxPackages) {
+		fmt.Fprintf(os.Stderr, "'%v': WARNING: %v\n", pkg, r.err)
+	} else if errors.Is(r.err, depth.ErrInvalidImportPath) {
+		fmt.Fprintf(w, "'%v': %v, expected a package import path such as 'strings' or './cmd/depth'\n", pkg, r.err)
+		return r.err
+	} else if r.err != nil {
+		fmt.Fprintf(w, "'%v': FATAL: %v\n", pkg, describeErr(pkg, r))
+		return r.err
+	}
+
+	if r.tree.Root.NoGoFiles {
+		ctx := r.tree.BuildContext()
+		fmt.Fprintf(os.Stderr, "'%v': WARNING: no buildable Go files for %s/%s, try -tags or -cgo\n", pkg, ctx.GOOS, ctx.GOARCH)
+	}
+
+	// Failed checks are returned once the result has been written, so it's still shown.
+	failed := checkPolicy(w, pkg, r.tree)
+	if options.Acyclic || options.AcyclicAll {
+		if err := checkAcyclic(w, pkg, r.tree, !options.AcyclicAll); failed == nil {
+			failed = err
+		}
+	}
+
+	if options.FanIn {
+		r.tree.ComputeFanIn()
+	}
+	if options.MinDepth {
+		r.tree.ComputeMinDepth()
+	}
+	if options.Height || options.MinHeight > 0 {
+		r.tree.ComputeHeight()
+	}
+	root := filterPkg(r.tree.Root, options)
+	if options.SubtreePkg != "" {
+		if root = root.Find(options.SubtreePkg); root == nil {
+			fmt.Fprintf(w, "'%v': %v is not depended on\n", pkg, options.SubtreePkg)
+			return failed
+		}
+	}
+
+	if options.BaselineFile != "" && options.UpdateBaseline {
+		if err := writeBaseline(w, options.BaselineFile, flatDeps(*root)); err != nil {
+			return err
+		}
+		return failed
+	} else if options.BaselineFile != "" {
+		if err := checkBaseline(w, pkg, options.BaselineFile, flatDeps(*root)); err != nil && !isCheckFailure(err) {
+			return err
+		} else if failed == nil {
+			failed = err
+		}
+	}
+
+	if err := writeOutput(w, pkg, r, root, options); err != nil {
+		return err
+	}
+	return failed
+}
+
+// writeOutput writes the resolved Tree of a single package, as its Root filtered by the
+// Options, in the format and mode selected by the Options.
+func writeOutput(w io.Writer, pkg string, r result, root *depth.Pkg, options *depth.Options) error {
+	if options.ExplainPkg != "" {
+		paths := root.ExplainPaths(options.ExplainPkg)
+		if formatName(options) == "json" {
+			return writeJSON(w, paths)
+		}
+		writeExplain(w, *root, paths, options.ShowPositions)
+		return nil
+	}
+
+	if options.PathFrom != "" {
+		paths := r.tree.PathsBetween(options.PathFrom, options.PathTo)
+		if formatName(options) == "json" {
+			return writeJSON(w, paths)
+		}
+		writeExplain(w, *root, paths, options.ShowPositions)
+		return nil
+	}
+
+	if options.SummaryJSON {
+		// Each summary is a single line, so that several packages can be read as JSON Lines.
+		return json.NewEncoder(w).Encode(summaryJSON{root.Name, root.Stats(), r.tree.EdgeStats()})
+	}
+
+	formatter, err := newFormatter(options)
+	if err != nil {
+		return err
+	}
+	if list := listPkgs(r.tree, options); list != nil {
+		if formatter, err = newListFormatter(options, list); err != nil {
+			return err
+		}
+		if err := formatter.Format(w, root); err != nil {
+			fmt.Fprintf(w, "'%v': FATAL: %v\n", pkg, err)
+			return err
+		}
+		return nil
+	}
+	if formatName(options) != defaultFormat {
+		return formatter.Format(w, root)
+	}
+
+	if options.Histogram {
+		writeHistogram(w, r.tree.DepthHistogram())
+		return nil
+	}
+
+	if options.MinGoVersion {
+		if v := r.tree.MinGoVersion(); v != "" {
+			fmt.Fprintf(w, "go %s\n", v)
 		} else {
-			sum.numExternal++
+			fmt.Fprintf(w, "'%v': no Go version is declared by the modules depended on\n", pkg)
+		}
+		return nil
+	}
+
+	if options.Metrics {
+		m, e := r.tree.GraphMetrics(), r.tree.EdgeStats()
+		fmt.Fprintf(w, "%d nodes, %d edges (%d production, %d test) | average out-degree: %.2f | density: %.4f\n",
+			m.Nodes,
+			m.Edges,
+			e.Production,
+			e.Test,
+			m.AvgOutDegree,
+			m.Density)
+		return nil
+	}
+
+	if options.ListConflicts {
+		writeConflicts(w, r.tree.VersionConflicts())
+		return nil
+	}
+
+	if options.ListLOC {
+		writeLOC(w, *root)
+		return nil
+	}
+
+	if options.CostPkg != "" {
+		writeCost(w, r.tree, options.CostPkg)
+		return nil
+	}
+
+	if err := formatter.Format(w, root); err != nil {
+		fmt.Fprintf(w, "'%v': FATAL: unable to format: %v\n", pkg, err)
+		return err
+	}
+	if !options.Quiet {
+		fmt.Fprintf(w, "Resolved <%s> in %s\n", pkg, r.elapsed)
+	}
+	return nil
+}
+
+// listPkgs returns the function listing the names of packages selected by the Options, such as
+// -list-stdlib or -topo, or nil if none is selected.
+func listPkgs(t *depth.Tree, options *depth.Options) func(root *depth.Pkg) ([]string, error) {
+	switch {
+	case options.ListStdlib:
+		return func(*depth.Pkg) ([]string, error) { return t.StdlibDeps(), nil }
+	case options.TopoSort:
+		return func(*depth.Pkg) ([]string, error) {
+			order, err := t.TopoSort()
+			if err != nil {
+				return nil, fmt.Errorf("unable to sort topologically: %w", err)
+			}
+			return order, nil
+		}
+	case options.ListTestOnly:
+		return func(*depth.Pkg) ([]string, error) { return t.TestOnlyDeps(), nil }
+	case options.ListLeaves:
+		return func(*depth.Pkg) ([]string, error) { return t.Leaves(), nil }
+	case options.PreviewMaxDepth > 0:
+		return func(*depth.Pkg) ([]string, error) { return t.WouldTruncate(options.PreviewMaxDepth), nil }
+	case options.ListFlat:
+		return func(root *depth.Pkg) ([]string, error) { return flatDeps(*root), nil }
+	}
+	return nil
+}
+
+// filterPkg applies the output filters of the Options to the Pkg provided, returning
+// the Pkg unmodified if none are set.
+func filterPkg(p *depth.Pkg, options *depth.Options) *depth.Pkg {
+	p = p.WithoutNoise()
+	if options.OnlyUnresolved {
+		p = p.Filter(func(p *depth.Pkg) bool {
+			return !p.Resolved
+		})
+	}
+	if options.OnlyTest {
+		p = p.Filter(func(p *depth.Pkg) bool {
+			return p.Test
+		})
+	}
+	if options.MinHeight > 0 {
+		p = p.Filter(func(p *depth.Pkg) bool {
+			return p.GraphHeight >= options.MinHeight
+		})
+	}
+	if options.CollapseStdlibInternal {
+		p = p.CollapseStdlibInternal()
+	}
+	return p
+}
+
+// writePkgSummary writes a summary of all packages in a tree
+func writePkgSummary(w io.Writer, pkg depth.Pkg, options *depth.Options) {
+	sum := pkg.Stats()
+	fmt.Fprintf(w, "%d dependencies (%d internal, %d external, %d testing) | max depth: %d | %d edges\n",
+		sum.Total,
+		sum.Internal,
+		sum.External,
+		sum.Testing,
+		sum.MaxDepth,
+		countEdges(pkg))
+
+	// The share of each kind gives a quick read of how coupled the package is to third parties.
+	if sum.Total > 0 {
+		fmt.Fprintf(w, "%d deps: %d%% internal, %d%% external, %d%% testing\n",
+			sum.Total,
+			percent(sum.Internal, sum.Total),
+			percent(sum.External, sum.Total),
+			percent(sum.Testing, sum.Total))
+	}
+
+	// Packages only belong to modules when resolved in module mode.
+	if modules := pkg.Modules(); len(modules) > 0 {
+		fmt.Fprintf(w, "%s across %s\n",
+			plural(countModulePkgs(pkg, modules), "external package"),
+			plural(len(modules), "module"))
+	}
+
+	if options.CountExternal {
+		fmt.Fprintf(w, "%d third-party (%d total including stdlib)\n",
+			pkg.ThirdParty(),
+			sum.Total)
+	}
+
+	for _, prefix := range options.CountPrefixes {
+		fmt.Fprintf(w, "%d matching %s\n", countPrefix(pkg, prefix), prefix)
+	}
+}
+
+// percent returns n as a percentage of total, rounded to the nearest whole number. Zero is
+// returned when total is zero.
+func percent(n, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return (n*200 + total) / (total * 2)
+}
+
+// countEdges returns the number of unique imports between two packages within the Pkg and
+// its dependencies, which shows how interconnected the packages are compared to their number.
+func countEdges(pkg depth.Pkg) int {
+	var count int
+	for _, to := range pkg.Edges {
+		if to.Omitted == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// countModulePkgs returns the number of unique dependencies of the Pkg belonging to one of
+// the modules provided.
+func countModulePkgs(pkg depth.Pkg, modules []string) int {
+	var count int
+	pkg.WalkPackages(func(p *depth.Pkg, depth int) bool {
+		if depth > 0 && p.Omitted == 0 && slices.Contains(modules, p.Module) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// plural returns the count provided followed by the noun, pluralized unless the count is one.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// countPrefix returns the number of unique, resolved dependencies of the Pkg whose name
+// starts with the prefix provided.
+func countPrefix(pkg depth.Pkg, prefix string) int {
+	var count int
+	pkg.WalkPackages(func(p *depth.Pkg, depth int) bool {
+		if depth > 0 && p.Resolved && p.Omitted == 0 && strings.HasPrefix(p.Name, prefix) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// newCompactJSON returns the compactJSON graph of the Pkg and its dependencies. The Pkg
+// is the first node, and the remaining nodes are in the order they are first imported.
+func newCompactJSON(p depth.Pkg) compactJSON {
+	c := compactJSON{Edges: [][2]int{}}
+	indexes := make(map[string]int)
+	index := func(p *depth.Pkg) int {
+		idx, ok := indexes[p.Name]
+		if !ok {
+			idx = len(c.Nodes)
+			indexes[p.Name] = idx
+			c.Nodes = append(c.Nodes, compactNode{Pkg: *p})
 		}
-		if pkg.Test {
-			sum.numTesting++
+		return idx
+	}
+
+	index(&p)
+	for from, to := range p.Edges {
+		c.Edges = append(c.Edges, [2]int{index(from), index(to)})
+	}
+	return c
+}
+
+// newFlatJSON returns a flatJSONPkg for each unique package within the Pkg, including the
+// Pkg itself, sorted by name.
+func newFlatJSON(p depth.Pkg) []flatJSONPkg {
+	pkgs := make(map[string]*flatJSONPkg)
+	p.Walk(func(dep *depth.Pkg, depth int) bool {
+		if dep.Omitted > 0 {
+			return true
 		}
-		if pkg.Depth > sum.maxDepth {
-			sum.maxDepth = pkg.Depth
+		f, ok := pkgs[dep.Name]
+		if !ok {
+			f = &flatJSONPkg{Name: dep.Name, Internal: dep.Internal, MinDepth: depth}
+			pkgs[dep.Name] = f
 		}
-		for _, p := range pkg.Deps {
-			collectSummary(sum, p, nameSet)
+		f.Test = f.Test || dep.Test
+		f.MinDepth = min(f.MinDepth, depth)
+		return true
+	})
+
+	// Edges are unique, so each counts a distinct importer.
+	for _, to := range p.Edges {
+		if f, ok := pkgs[to.Name]; ok {
+			f.ImportedBy++
 		}
 	}
+
+	flat := make([]flatJSONPkg, 0, len(pkgs))
+	for _, name := range slices.Sorted(maps.Keys(pkgs)) {
+		flat = append(flat, *pkgs[name])
+	}
+	return flat
+}
+
+// newIDJSON returns the idJSON tree of the Pkg and its dependencies.
+func newIDJSON(p depth.Pkg) idJSON {
+	names := make(map[string]struct{})
+	p.Walk(func(dep *depth.Pkg, _ int) bool {
+		names[dep.Name] = struct{}{}
+		return true
+	})
+	sorted := slices.Sorted(maps.Keys(names))
+	ids := make(map[string]int, len(sorted))
+	for idx, name := range sorted {
+		ids[name] = idx
+	}
+	return newIDJSONRec(p, ids, nil)
+}
+
+// newIDJSONRec recursively converts the Pkg to an idJSON, using the IDs of each name
+// provided.
+func newIDJSONRec(p depth.Pkg, ids map[string]int, parentID *int) idJSON {
+	n := idJSON{Pkg: p, ID: ids[p.Name], ParentID: parentID, Deps: []idJSON{}}
+	for _, d := range p.Deps {
+		n.Deps = append(n.Deps, newIDJSONRec(d, ids, &n.ID))
+	}
+	return n
 }
 
-// writePkgJSON writes the full Pkg as JSON to the provided Writer.
-func writePkgJSON(w io.Writer, p depth.Pkg) error {
+// writeJSON writes the value provided as indented JSON to the Writer.
+func writeJSON(w io.Writer, v any) error {
 	e := json.NewEncoder(w)
 	e.SetIndent("", "  ")
-	return e.Encode(p)
+	return e.Encode(v)
 }
 
-func writePkg(w io.Writer, p depth.Pkg) {
-	fmt.Fprintf(w, "%s\n", p.String())
+func writePkg(w io.Writer, p depth.Pkg, options *depth.Options) {
+	label := pkgLabel(w, p, options)
+	fmt.Fprintf(w, "%s\n", label(p))
 
 	for idx, d := range p.Deps {
-		writePkgRec(w, d, []bool{true}, idx == len(p.Deps)-1)
+		writePkgRec(w, d, []bool{true}, idx == len(p.Deps)-1, label)
 	}
 }
 
 // writePkg recursively prints a Pkg and its dependencies to the Writer provided.
-func writePkgRec(w io.Writer, p depth.Pkg, closed []bool, isLast bool) {
+func writePkgRec(w io.Writer, p depth.Pkg, closed []bool, isLast bool, label func(depth.Pkg) string) {
 	var prefix string
 
 	for _, c := range closed {
@@ -181,20 +1032,281 @@ func writePkgRec(w io.Writer, p depth.Pkg, closed []bool, isLast bool) {
 		prefix += outputPrefix
 	}
 
-	fmt.Fprintf(w, "%v%v\n", prefix, p.String())
+	fmt.Fprintf(w, "%v%v\n", prefix, label(p))
 
 	for idx, d := range p.Deps {
-		writePkgRec(w, d, closed, idx == len(p.Deps)-1)
+		writePkgRec(w, d, closed, idx == len(p.Deps)-1, label)
 	}
 }
 
-// writeExplain shows possible paths for a given package.
-func writeExplain(w io.Writer, pkg depth.Pkg, stack []string, explain string) {
-	stack = append(stack, pkg.Name)
-	if pkg.Name == explain {
-		fmt.Fprintln(w, strings.Join(stack, " -> "))
+// pkgLabel returns a function that renders the text output of a Pkg written to w, within
+// the tree of the root Pkg provided.
+func pkgLabel(w io.Writer, root depth.Pkg, options *depth.Options) func(depth.Pkg) string {
+	f, ok := w.(*os.File)
+	color := options.Color.Enabled(ok && isTerminal(f))
+
+	prefix := options.TrimPrefix
+	if options.TrimRootPrefix {
+		// The import path is preferred over the name, which may be a label.
+		prefix = root.Name
+		if root.Raw != nil && root.Raw.ImportPath != "" {
+			prefix = root.Raw.ImportPath
+		}
+		if root.Module != "" {
+			prefix = root.Module
+		} else if root.Tree != nil && root.Tree.MainModule() != "" && build.IsLocalImport(root.Name) {
+			prefix = root.Tree.MainModule()
+		}
+	}
+
+	return func(p depth.Pkg) string {
+		name := p.Name
+		if p.Depth > 0 {
+			name = trimName(name, prefix)
+		}
+		if len(options.HighlightPatterns) > 0 && depth.MatchesPatterns(p.Name, options.HighlightPatterns, nil) {
+			name = highlight(name, color)
+		} else if color {
+			name = pkgColor(p) + name + colorReset
+		}
+		name += strings.TrimPrefix(p.String(), p.Name)
+		if options.Height && p.Omitted == 0 {
+			name += fmt.Sprintf(" (height %d)", p.GraphHeight)
+		}
+		if options.ShowPositions {
+			name += importPositions(p.Parent, p.Name)
+		}
+		return name
+	}
+}
+
+// trimName returns the name of a package relative to the prefix provided, such as
+// ./internal/foo for github.com/org/repo/internal/foo. Names outside of the prefix,
+// or equal to it, are returned unmodified.
+func trimName(name, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" || !strings.HasPrefix(name, prefix+"/") {
+		return name
+	}
+	return "./" + strings.TrimPrefix(name, prefix+"/")
+}
+
+// pkgColor returns the ANSI escape code of the color that the Pkg is written in.
+func pkgColor(p depth.Pkg) string {
+	switch {
+	case !p.Resolved:
+		return colorUnresolved
+	case p.Test:
+		return colorTest
+	case p.Internal:
+		return colorInternal
+	default:
+		return colorExternal
+	}
+}
+
+// highlight marks the name provided, using ANSI escape codes when the output is colored
+// and plain markers otherwise.
+func highlight(name string, color bool) string {
+	if color {
+		return highlightStart + name + highlightEnd
+	}
+	return ">>" + name + "<<"
+}
+
+// isTerminal returns true if the File provided is a character device, such as an
+// interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// maxHistogramWidth is the length of the longest bar written by writeHistogram.
+const maxHistogramWidth = 50
+
+// writeHistogram writes the depth histogram provided as a bar chart, with a row for each
+// depth from the shallowest to the deepest.
+func writeHistogram(w io.Writer, hist map[int]int) {
+	var maxDepth, maxCount int
+	for depth, count := range hist {
+		maxDepth = max(maxDepth, depth)
+		maxCount = max(maxCount, count)
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		count := hist[depth]
+		width := count
+		if maxCount > maxHistogramWidth {
+			// Scale the bars to fit, while keeping any non-empty depth visible.
+			width = count * maxHistogramWidth / maxCount
+			if width == 0 && count > 0 {
+				width = 1
+			}
+		}
+		fmt.Fprintf(w, "%3d | %s %d\n", depth, strings.Repeat("#", width), count)
+	}
+}
+
+// errBaselineGrowth is returned when packages are depended on that are missing from the baseline.
+var errBaselineGrowth = errors.New("dependencies added since the baseline")
+
+// flatDeps returns the sorted, unique names of the dependencies of the Pkg.
+func flatDeps(root depth.Pkg) []string {
+	var names []string
+	root.WalkPackages(func(p *depth.Pkg, depth int) bool {
+		if depth > 0 && p.Omitted == 0 {
+			names = append(names, p.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// checkBaseline writes each of the dependencies provided that is missing from the baseline
+// file, returning errBaselineGrowth if there are any.
+func checkBaseline(w io.Writer, pkg, file string, deps []string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Fprintf(w, "'%v': FATAL: unable to read baseline: %v\n", pkg, err)
+		return err
+	}
+	defer f.Close()
+
+	baseline, err := readPkgNames(f)
+	if err != nil {
+		fmt.Fprintf(w, "'%v': FATAL: unable to read baseline: %v\n", pkg, err)
+		return err
+	}
+
+	var added int
+	for _, name := range deps {
+		if !slices.Contains(baseline, name) {
+			fmt.Fprintf(w, "'%v': %s is not in the baseline\n", pkg, name)
+			added++
+		}
+	}
+	if added > 0 {
+		return errBaselineGrowth
+	}
+	return nil
+}
+
+// writeBaseline writes the dependencies provided to the baseline file, one per line.
+func writeBaseline(w io.Writer, file string, deps []string) error {
+	var b strings.Builder
+	for _, name := range deps {
+		b.WriteString(name + "\n")
+	}
+	if err := os.WriteFile(file, []byte(b.String()), 0644); err != nil {
+		fmt.Fprintf(w, "FATAL: unable to write baseline: %v\n", err)
+		return err
+	}
+	fmt.Fprintf(w, "Wrote %d packages to %s\n", len(deps), file)
+	return nil
+}
+
+// writeConflicts writes each module seen at more than one version, sorted by path, along
+// with its versions.
+func writeConflicts(w io.Writer, conflicts map[string][]string) {
+	if len(conflicts) == 0 {
+		fmt.Fprintln(w, "No modules are required at more than one version")
+		return
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(conflicts)) {
+		fmt.Fprintf(w, "%s: %s\n", path, strings.Join(conflicts[path], ", "))
+	}
+}
+
+// writeLOC writes the lines of code of each unique dependency of the Pkg, from largest to
+// smallest, followed by their total.
+func writeLOC(w io.Writer, root depth.Pkg) {
+	// Only the occurrence of each package that was imported has its lines counted.
+	lines := make(map[string]int)
+	root.Walk(func(p *depth.Pkg, depth int) bool {
+		if depth > 0 && p.Omitted == 0 {
+			lines[p.Name] = max(lines[p.Name], p.LinesOfCode)
+		}
+		return true
+	})
+
+	names := make([]string, 0, len(lines))
+	var total int
+	for name, n := range lines {
+		names = append(names, name)
+		total += n
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if lines[names[i]] != lines[names[j]] {
+			return lines[names[i]] > lines[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		fmt.Fprintf(w, "%8d  %s\n", lines[name], name)
+	}
+	fmt.Fprintf(w, "%8d  total\n", total)
+}
+
+// writeCost shows the packages that would be removed from the tree along with the target.
+func writeCost(w io.Writer, t *depth.Tree, target string) {
+	deps := t.ExclusiveDeps(target)
+	fmt.Fprintf(w, "%d packages are only imported through %s\n", len(deps), target)
+	for _, dep := range deps {
+		fmt.Fprintf(w, "%s%s\n", outputClosedPadding, dep)
+	}
+}
+
+// writeExplain writes the import paths of the Pkg provided, marking each package imported
+// only by the tests of the previous package with [test], and with the positions of each
+// import if requested.
+func writeExplain(w io.Writer, root depth.Pkg, paths [][]string, positions bool) {
+	test := make(map[[2]string]bool)
+	for from, to := range root.Edges {
+		test[[2]string{from.Name, to.Name}] = to.Test
+	}
+
+	// Positions are taken from the occurrence of each package that was imported, preferring
+	// the one that was expanded.
+	imported := make(map[string]*depth.Pkg)
+	if positions {
+		root.Walk(func(p *depth.Pkg, _ int) bool {
+			if prev, ok := imported[p.Name]; p.Raw != nil && (!ok || len(prev.Deps) == 0) {
+				imported[p.Name] = p
+			}
+			return true
+		})
+	}
+
+	for _, path := range paths {
+		labels := slices.Clone(path)
+		for idx := 1; idx < len(path); idx++ {
+			if test[[2]string{path[idx-1], path[idx]}] {
+				labels[idx] += " [test]"
+			}
+			if positions {
+				labels[idx] += importPositions(imported[path[idx-1]], path[idx])
+			}
+		}
+		fmt.Fprintln(w, strings.Join(labels, " -> "))
 	}
-	for _, p := range pkg.Deps {
-		writeExplain(w, p, stack, explain)
+}
+
+// importPositions returns the positions at which the parent Pkg imports the package named,
+// formatted for output, or an empty string if they aren't known.
+func importPositions(parent *depth.Pkg, name string) string {
+	if parent == nil {
+		return ""
+	}
+
+	positions := parent.ImportedFrom(name)
+	if len(positions) == 0 {
+		return ""
 	}
+	return " [" + strings.Join(positions, ", ") + "]"
 }
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/adapap/depth"
@@ -35,12 +43,61 @@ func Test_parse(t *testing.T) {
 
 		assert.Equal(t, tc.internal, tr.ResolveInternal)
 		assert.Equal(t, tc.test, tr.ResolveTest)
+		assert.Equal(t, tc.test, tr.ResolveXTest)
 		assert.Equal(t, tc.depth, tr.MaxDepth)
 		assert.Equal(t, tc.json, options.OutputJSON)
 		assert.Equal(t, tc.explain, options.ExplainPkg)
 	}
 }
 
+func Test_parseSort(t *testing.T) {
+	tr, _ := parse(nil)
+	assert.Equal(t, depth.SortInternalFirst, tr.SortMode)
+
+	for _, mode := range []depth.SortMode{depth.SortAlphabetical, depth.SortByDepth, depth.SortUnsorted} {
+		tr, _ := parse([]string{"-sort", mode.String()})
+		assert.Equal(t, mode, tr.SortMode)
+	}
+}
+
+func Test_parseFindOnly(t *testing.T) {
+	tr, _ := parse([]string{"-findonly"})
+	assert.True(t, tr.FindOnly)
+}
+
+func Test_parseCgo(t *testing.T) {
+	tr, _ := parse(nil)
+	assert.Nil(t, tr.CgoEnabled)
+
+	for _, enabled := range []bool{true, false} {
+		tr, _ := parse([]string{fmt.Sprintf("-cgo=%v", enabled)})
+		if assert.NotNil(t, tr.CgoEnabled) {
+			assert.Equal(t, enabled, *tr.CgoEnabled)
+			assert.Equal(t, enabled, tr.BuildContext().CgoEnabled)
+		}
+	}
+}
+
+func Test_parseTestSplit(t *testing.T) {
+	tests := []struct {
+		args  []string
+		test  bool
+		xtest bool
+	}{
+		{[]string{"-test"}, true, true},
+		{[]string{"-test", "-xtest=false"}, true, false},
+		{[]string{"-xtest"}, false, true},
+		{[]string{"-test", "-xtest"}, true, true},
+		{nil, false, false},
+	}
+
+	for _, tc := range tests {
+		tr, _ := parse(tc.args)
+		assert.Equal(t, tc.test, tr.ResolveTest, tc.args)
+		assert.Equal(t, tc.xtest, tr.ResolveXTest, tc.args)
+	}
+}
+
 func Example_handlePkgsStrings() {
 	var tree depth.Tree
 
@@ -60,10 +117,9 @@ func Example_handlePkgsStrings() {
 }
 
 func Example_handlePkgsTestStrings() {
-	var tree depth.Tree
-	tree.ResolveTest = true
+	tree, _ := parse([]string{"-test"})
 
-	_ = handlePkgs(&tree, &depth.Options{PackageNames: []string{"strings"}})
+	_ = handlePkgs(tree, &depth.Options{PackageNames: []string{"strings"}})
 	// Output:
 	// strings
 	//   ├ bytes
@@ -114,7 +170,74 @@ func Example_handlePkgsUnknown() {
 
 	_ = handlePkgs(&tree, &depth.Options{PackageNames: []string{"notreal"}})
 	// Output:
-	// 'notreal': FATAL: unable to resolve root package
+	// 'notreal': FATAL: unable to resolve root package: package not found, check the import path or run 'go get' to add its module
+}
+
+// captureStdout returns everything written to os.Stdout while calling fn.
+func captureStdout(t *testing.T, fn func()) string {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+
+	// The pipe is read concurrently, so fn doesn't block once its buffer is full.
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func Test_handlePkgsParallel(t *testing.T) {
+	pkgs := []string{"strings", "errors", "io", "sort", "unicode/utf8"}
+	sequential := captureStdout(t, func() {
+		assert.NoError(t, handlePkgs(&depth.Tree{}, &depth.Options{PackageNames: pkgs, Quiet: true}))
+	})
+	parallel := captureStdout(t, func() {
+		assert.NoError(t, handlePkgs(&depth.Tree{}, &depth.Options{PackageNames: pkgs, Quiet: true, Parallel: true, MaxConcurrency: 4}))
+	})
+	// Timings of the imports differ between runs.
+	elapsed := regexp.MustCompile(` \([0-9.]+[nµm]?s\)`)
+	sequential = elapsed.ReplaceAllString(sequential, "")
+	parallel = elapsed.ReplaceAllString(parallel, "")
+	assert.Equal(t, sequential, parallel)
+
+	// Each tree is written whole, in the order the packages were provided.
+	var roots []string
+	for _, line := range strings.Split(strings.TrimSpace(parallel), "\n") {
+		if !strings.HasPrefix(line, " ") {
+			roots = append(roots, line)
+		}
+	}
+	assert.Equal(t, pkgs, roots)
+}
+
+func Test_describeErr(t *testing.T) {
+	tests := []struct {
+		name  string
+		cause error
+		want  string
+	}{
+		{"./missing", errors.New("cannot find package \"./missing\" in:\n\t/src/missing"), "unable to resolve root package: no package found in the directory, check that the path is correct"},
+		{"strings/nope", errors.New("package strings/nope is not in std (/go/src/strings/nope)"), "unable to resolve root package: package not found, check the import path or run 'go get' to add its module"},
+		{"./multi", &build.MultiplePackageError{Dir: "/src/multi", Packages: []string{"a", "b"}, Files: []string{"a.go", "b.go"}}, "unable to resolve root package: found packages a (a.go) and b (b.go) in /src/multi, a directory may only contain a single package"},
+		{"github.com/foo/bar", errors.New("module lookup disabled\nmore details"), "unable to resolve root package: module lookup disabled"},
+	}
+	for _, tc := range tests {
+		tree := depth.Tree{Root: &depth.Pkg{Name: tc.name, Err: tc.cause}}
+		if got := describeErr(tc.name, result{tree: &tree, err: depth.ErrRootPkgNotResolved}); got != tc.want {
+			t.Fatalf("Unexpected description of %v, expected=%q, got=%q", tc.name, tc.want, got)
+		}
+	}
+
+	if got := describeErr("strings", result{err: depth.ErrTimeout}); got != depth.ErrTimeout.Error() {
+		t.Fatalf("Unexpected description, expected=%q, got=%q", depth.ErrTimeout.Error(), got)
+	}
 }
 
 func Example_handlePkgsJson() {
@@ -186,6 +309,36 @@ func Example_handlePkgsJson() {
 
 }
 
+func Test_handlePkgsJSONEnvelope(t *testing.T) {
+	// The bare Pkg remains the default.
+	out := captureStdout(t, func() {
+		assert.NoError(t, handlePkgs(&depth.Tree{}, &depth.Options{PackageNames: []string{"strings"}, OutputJSON: true}))
+	})
+	var bare map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(out), &bare))
+	assert.Equal(t, "strings", bare["name"])
+	assert.NotContains(t, bare, "version")
+
+	out = captureStdout(t, func() {
+		assert.NoError(t, handlePkgs(&depth.Tree{}, &depth.Options{PackageNames: []string{"strings"}, OutputJSON: true, JSONEnvelope: true}))
+	})
+	var envelope struct {
+		Version int         `json:"version"`
+		Root    depth.Pkg   `json:"root"`
+		Stats   depth.Stats `json:"stats"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(out), &envelope))
+	assert.Equal(t, jsonVersion, envelope.Version)
+	assert.Equal(t, "strings", envelope.Root.Name)
+	assert.NotEmpty(t, envelope.Root.Deps)
+	assert.Equal(t, len(envelope.Root.Deps), envelope.Stats.Total)
+
+	// The envelope holds nothing else.
+	var keys map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal([]byte(out), &keys))
+	assert.Len(t, keys, 3)
+}
+
 func Example_handlePkgsExplain() {
 	var tree depth.Tree
 
@@ -194,3 +347,510 @@ func Example_handlePkgsExplain() {
 	// github.com/adapap/depth/cmd/depth -> strings
 	// github.com/adapap/depth/cmd/depth -> github.com/adapap/depth -> strings
 }
+
+func Example_newCompactJSON() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "github.com/foo/bar", Resolved: true, Deps: []depth.Pkg{
+			{Name: "strings", Internal: true, Resolved: true},
+		}},
+	}}
+
+	b, _ := json.Marshal(newCompactJSON(p))
+	fmt.Println(string(b))
+	// Output:
+	// {"nodes":[{"name":"root","internal":false,"resolved":true},{"name":"strings","internal":true,"resolved":true},{"name":"github.com/foo/bar","internal":false,"resolved":true}],"edges":[[0,1],[0,2],[2,1]]}
+}
+
+func Example_newIDJSON() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "github.com/foo/bar", Resolved: true, Deps: []depth.Pkg{
+			{Name: "strings", Internal: true, Resolved: true},
+		}},
+	}}
+
+	b, _ := json.Marshal(newIDJSON(p))
+	fmt.Println(string(b))
+	// Output:
+	// {"name":"root","internal":false,"resolved":true,"id":1,"deps":[{"name":"strings","internal":true,"resolved":true,"id":2,"parentId":1,"deps":[]},{"name":"github.com/foo/bar","internal":false,"resolved":true,"id":0,"parentId":1,"deps":[{"name":"strings","internal":true,"resolved":true,"id":2,"parentId":0,"deps":[]}]}]}
+}
+
+func Example_newFlatJSON() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "github.com/foo/bar", Resolved: true, Deps: []depth.Pkg{
+			{Name: "strings", Internal: true, Resolved: true},
+			{Name: "github.com/foo/baz", Resolved: true, Test: true},
+		}},
+	}}
+
+	b, _ := json.Marshal(newFlatJSON(p))
+	fmt.Println(string(b))
+	// Output:
+	// [{"name":"github.com/foo/bar","internal":false,"test":false,"minDepth":1,"importedBy":1},{"name":"github.com/foo/baz","internal":false,"test":true,"minDepth":2,"importedBy":1},{"name":"root","internal":false,"test":false,"minDepth":0,"importedBy":0},{"name":"strings","internal":true,"test":false,"minDepth":1,"importedBy":2}]
+}
+
+func Test_newFormatter(t *testing.T) {
+	tests := []struct {
+		options  depth.Options
+		expected depth.Formatter
+	}{
+		{depth.Options{}, treeFormatter{}},
+		{depth.Options{Format: "json"}, jsonFormatter{}},
+		{depth.Options{OutputJSON: true}, jsonFormatter{}},
+		{depth.Options{Format: "tree", OutputJSON: true}, treeFormatter{}},
+		{depth.Options{OutputGraphML: true}, depth.FormatterFunc(nil)},
+		{depth.Options{Format: "markdown"}, depth.FormatterFunc(nil)},
+		{depth.Options{Format: "dot"}, depth.FormatterFunc(nil)},
+		{depth.Options{OutputDOT: true}, depth.FormatterFunc(nil)},
+		{depth.Options{OutputAdjacency: true}, depth.FormatterFunc(nil)},
+		{depth.Options{Template: "{{.Name}}"}, templateFormatter{}},
+		{depth.Options{Format: "json", RootLabel: "project"}, rootLabelFormatter{}},
+	}
+
+	for _, tc := range tests {
+		f, err := newFormatter(&tc.options)
+		assert.NoError(t, err)
+		assert.IsType(t, tc.expected, f)
+	}
+
+	_, err := newFormatter(&depth.Options{Format: "csv"})
+	assert.EqualError(t, err, `unknown format "csv", expected one of: adjacency, dot, graphml, json, markdown, svg, tree`)
+
+	_, err = newFormatter(&depth.Options{Template: "{{.Name"})
+	assert.ErrorContains(t, err, "invalid template: template: template:1: unclosed action")
+
+	_, options := parse([]string{"-dot"})
+	assert.Equal(t, "dot", formatName(options))
+
+	_, err = newFormatter(&depth.Options{Format: "json", Template: "{{.Name}}"})
+	assert.EqualError(t, err, "a template can only be used with the tree format, not json")
+}
+
+func Test_newListFormatter(t *testing.T) {
+	p := depth.Pkg{Name: "root"}
+	list := func(*depth.Pkg) ([]string, error) { return []string{"errors", "strings"}, nil }
+
+	f, err := newListFormatter(&depth.Options{}, list)
+	assert.NoError(t, err)
+	var b strings.Builder
+	assert.NoError(t, f.Format(&b, &p))
+	assert.Equal(t, "errors\nstrings\n", b.String())
+
+	f, err = newListFormatter(&depth.Options{OutputJSON: true}, list)
+	assert.NoError(t, err)
+	b.Reset()
+	assert.NoError(t, f.Format(&b, &p))
+	assert.JSONEq(t, `["errors", "strings"]`, b.String())
+
+	// An empty list is still a JSON array.
+	f, _ = newListFormatter(&depth.Options{Format: "json"}, func(*depth.Pkg) ([]string, error) { return nil, nil })
+	b.Reset()
+	assert.NoError(t, f.Format(&b, &p))
+	assert.Equal(t, "[]\n", b.String())
+
+	_, err = newListFormatter(&depth.Options{Format: "dot"}, list)
+	assert.EqualError(t, err, "packages can only be listed in the tree or json format, not dot")
+
+	_, err = newListFormatter(&depth.Options{Template: "{{.Name}}"}, list)
+	assert.EqualError(t, err, "a template can't be used to list packages")
+}
+
+func Test_newFormatterOmitted(t *testing.T) {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "... (2 more)", Resolved: true, Depth: 1, Omitted: 2},
+	}}
+
+	// Placeholders are only shown in the formats meant to be read as text.
+	for _, options := range []depth.Options{
+		{Format: "json"},
+		{Format: "json", JSONCompact: true},
+		{Format: "json", JSONIDs: true},
+		{Format: "json", JSONFlat: true},
+		{Format: "graphml"},
+		{Format: "svg"},
+		{Format: "dot"},
+		{Format: "adjacency"},
+		{Template: "{{.Name}}", Quiet: true},
+	} {
+		f, err := newFormatter(&options)
+		assert.NoError(t, err)
+
+		var b strings.Builder
+		assert.NoError(t, f.Format(&b, &p))
+		assert.Contains(t, b.String(), "strings")
+		assert.NotContains(t, b.String(), "more", "format %q", formatName(&options))
+	}
+
+	for _, options := range []depth.Options{{Quiet: true}, {Format: "markdown"}} {
+		f, err := newFormatter(&options)
+		assert.NoError(t, err)
+
+		var b strings.Builder
+		assert.NoError(t, f.Format(&b, &p))
+		assert.Contains(t, b.String(), "... (2 more)")
+	}
+}
+
+func Example_templateFormatter() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "github.com/foo/bar", Depth: 1, Deps: []depth.Pkg{
+			{Name: "strings", Internal: true, Resolved: true, Depth: 2},
+		}},
+	}}
+
+	f, _ := newFormatter(&depth.Options{Template: "{{.Name}} ({{.Depth}}){{if not .Resolved}} !{{end}}", Quiet: true})
+	f.Format(os.Stdout, &p)
+	// Output:
+	// root (0)
+	// strings (1)
+	// github.com/foo/bar (1) !
+	// strings (2)
+}
+
+func Example_rootLabelFormatter() {
+	p := depth.Pkg{Name: "./...", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+	}}
+
+	f, _ := newFormatter(&depth.Options{Format: "dot", RootLabel: "my-project"})
+	_ = f.Format(os.Stdout, &p)
+	// Output:
+	// digraph deps {
+	//   "my-project" [fillcolor="lightblue", style="filled"];
+	//   "strings" [fillcolor="lightgrey", style="filled"];
+	//   "my-project" -> "strings";
+	// }
+}
+
+func Example_writePkgSummaryCountPrefixes() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "golang.org/x/mod", Resolved: true, Depth: 1, Deps: []depth.Pkg{
+			{Name: "golang.org/x/sys", Resolved: true, Depth: 2},
+			{Name: "golang.org/x/missing", Depth: 2},
+		}},
+		{Name: "golang.org/x/sys", Resolved: true, Depth: 1},
+	}}
+
+	writePkgSummary(os.Stdout, p, &depth.Options{CountPrefixes: []string{"golang.org/x/", "github.com/"}})
+	// Output:
+	// 4 dependencies (1 internal, 3 external, 0 testing) | max depth: 2 | 5 edges
+	// 4 deps: 25% internal, 75% external, 0% testing
+	// 2 matching golang.org/x/
+	// 0 matching github.com/
+}
+
+func Example_writePkgSummaryCountExternal() {
+	// The first-party package is marked internal, as by an InternalFunc, but is still
+	// counted as third-party since it isn't part of the standard library.
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1, Raw: &build.Package{Goroot: true}},
+		{Name: "github.com/org/root/util", Internal: true, Resolved: true, Depth: 1, Raw: &build.Package{}},
+		{Name: "golang.org/x/sys", Resolved: true, Depth: 1, Raw: &build.Package{}},
+	}}
+
+	writePkgSummary(os.Stdout, p, &depth.Options{CountExternal: true})
+	// Output:
+	// 3 dependencies (2 internal, 1 external, 0 testing) | max depth: 1 | 3 edges
+	// 3 deps: 67% internal, 33% external, 0% testing
+	// 2 third-party (3 total including stdlib)
+}
+
+func Example_writePkgSummaryModules() {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "golang.org/x/mod/semver", Module: "golang.org/x/mod", Resolved: true, Depth: 1},
+		{Name: "golang.org/x/mod/modfile", Module: "golang.org/x/mod", Resolved: true, Depth: 1},
+		{Name: "example.com/root/util", Module: "example.com/root", Resolved: true, Depth: 1},
+	}}
+	p.Module = "example.com/root"
+
+	// Packages of the root's own module are external, but don't belong to the modules listed.
+	writePkgSummary(os.Stdout, p, &depth.Options{})
+	// Output:
+	// 4 dependencies (1 internal, 3 external, 0 testing) | max depth: 1 | 4 edges
+	// 4 deps: 25% internal, 75% external, 0% testing
+	// 2 external packages across 1 module
+}
+
+func Test_plural(t *testing.T) {
+	assert.Equal(t, "0 modules", plural(0, "module"))
+	assert.Equal(t, "1 module", plural(1, "module"))
+	assert.Equal(t, "2 external packages", plural(2, "external package"))
+}
+
+func Example_writePkgSummaryEmpty() {
+	p := depth.Pkg{Name: "root", Resolved: true}
+
+	writePkgSummary(os.Stdout, p, &depth.Options{})
+	// Output:
+	// 0 dependencies (0 internal, 0 external, 0 testing) | max depth: 0 | 0 edges
+}
+
+func Test_percent(t *testing.T) {
+	assert.Equal(t, 0, percent(0, 0))
+	assert.Equal(t, 0, percent(0, 73))
+	assert.Equal(t, 60, percent(44, 73))
+	assert.Equal(t, 67, percent(2, 3))
+	assert.Equal(t, 100, percent(5, 5))
+}
+
+func Test_trimName(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{"github.com/org/repo/internal/foo", "github.com/org/repo", "./internal/foo"},
+		{"github.com/org/repo/internal/foo", "github.com/org/repo/", "./internal/foo"},
+		{"github.com/org/repo", "github.com/org/repo", "github.com/org/repo"},
+		{"github.com/org/repository", "github.com/org/repo", "github.com/org/repository"},
+		{"strings", "", "strings"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, trimName(tc.name, tc.prefix))
+	}
+}
+
+func Test_writePkgColor(t *testing.T) {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "testing", Internal: true, Resolved: true, Test: true, Depth: 1},
+		{Name: "github.com/foo/bar", Depth: 1},
+	}}
+
+	var b strings.Builder
+	writePkg(&b, p, &depth.Options{Color: depth.ColorAlways})
+	expected := "\033[32mroot\033[0m\n" +
+		"  ├ \033[36mstrings\033[0m\n" +
+		"  ├ \033[2mtesting\033[0m\n" +
+		"  └ \033[31mgithub.com/foo/bar\033[0m (unresolved)\n"
+	assert.Equal(t, expected, b.String())
+
+	// Without color, the output is plain text.
+	b.Reset()
+	writePkg(&b, p, &depth.Options{Color: depth.ColorNever})
+	assert.Equal(t, "root\n  ├ strings\n  ├ testing\n  └ github.com/foo/bar (unresolved)\n", b.String())
+
+	var mode depth.ColorMode
+	assert.NoError(t, mode.Set("never"))
+	assert.Equal(t, depth.ColorNever, mode)
+	assert.Error(t, mode.Set("sometimes"))
+}
+
+func Test_writePkgHighlight(t *testing.T) {
+	p := depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "github.com/foo/bar", Resolved: true, Depth: 1, Deps: []depth.Pkg{
+			{Name: "github.com/foo/baz", Resolved: true, Depth: 2},
+		}},
+	}}
+
+	// Matching packages are marked, without filtering the rest of the tree.
+	var b strings.Builder
+	writePkg(&b, p, &depth.Options{HighlightPatterns: []string{"github.com/foo/"}, Color: depth.ColorNever})
+	assert.Equal(t, "root\n  ├ strings\n  └ >>github.com/foo/bar<<\n    └ >>github.com/foo/baz<<\n", b.String())
+
+	// Colored output highlights them instead.
+	b.Reset()
+	writePkg(&b, p, &depth.Options{HighlightPatterns: []string{"strings", "baz"}, Color: depth.ColorAlways})
+	expected := "\033[32mroot\033[0m\n" +
+		"  ├ " + highlightStart + "strings" + highlightEnd + "\n" +
+		"  └ \033[32mgithub.com/foo/bar\033[0m\n" +
+		"    └ " + highlightStart + "github.com/foo/baz" + highlightEnd + "\n"
+	assert.Equal(t, expected, b.String())
+
+	tr, options := parse([]string{"-highlight", "strings,baz"})
+	assert.NotNil(t, tr)
+	assert.Equal(t, []string{"strings", "baz"}, options.HighlightPatterns)
+}
+
+func Test_checkBaseline(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "baseline.txt")
+	var b strings.Builder
+	if err := writeBaseline(&b, file, []string{"errors", "strings"}); err != nil {
+		t.Fatalf("Unexpected error writing baseline: %v", err)
+	}
+	assert.Equal(t, "Wrote 2 packages to "+file+"\n", b.String())
+
+	// Order and whitespace are ignored, and packages may be removed.
+	assert.NoError(t, os.WriteFile(file, []byte("  strings\n\nerrors  \nio\n"), 0644))
+	b.Reset()
+	assert.NoError(t, checkBaseline(&b, "root", file, []string{"errors", "strings"}))
+	assert.Empty(t, b.String())
+
+	b.Reset()
+	assert.Equal(t, errBaselineGrowth, checkBaseline(&b, "root", file, []string{"errors", "fmt", "strings"}))
+	assert.Equal(t, "'root': fmt is not in the baseline\n", b.String())
+}
+
+func Test_checkAcyclic(t *testing.T) {
+	tr := &depth.Tree{ModulePrefix: "github.com/org/root", Root: &depth.Pkg{Name: "github.com/org/root", Deps: []depth.Pkg{
+		{Name: "github.com/foo/bar", Deps: []depth.Pkg{
+			{Name: "github.com/foo/baz", Deps: []depth.Pkg{
+				{Name: "github.com/foo/bar", Test: true},
+			}},
+		}},
+	}}}
+
+	var b strings.Builder
+	assert.NoError(t, checkAcyclic(&b, "root", tr, true))
+	assert.Empty(t, b.String())
+
+	err := checkAcyclic(&b, "root", tr, false)
+	assert.ErrorIs(t, err, depth.ErrImportCycle)
+	assert.Equal(t, "'root': import cycle: github.com/foo/bar -> github.com/foo/baz -> github.com/foo/bar\n", b.String())
+}
+
+func Test_readPkgNames(t *testing.T) {
+	names, err := readPkgNames(strings.NewReader("strings\n\n  # a comment\n  net/http  \n./cmd/depth\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"strings", "net/http", "./cmd/depth"}, names)
+}
+
+func Example_writeHistogram() {
+	writeHistogram(os.Stdout, map[int]int{1: 3, 3: 1})
+	// Output:
+	//   1 | ### 3
+	//   2 |  0
+	//   3 | # 1
+}
+
+func Example_writeLOC() {
+	p := depth.Pkg{Name: "root", LinesOfCode: 10, Deps: []depth.Pkg{
+		{Name: "strings", LinesOfCode: 2444},
+		{Name: "github.com/foo/bar", LinesOfCode: 120, Deps: []depth.Pkg{
+			{Name: "strings"},
+		}},
+	}}
+	writeLOC(os.Stdout, p)
+	// Output:
+	//     2444  strings
+	//      120  github.com/foo/bar
+	//     2564  total
+}
+
+func Test_writeResultPolicy(t *testing.T) {
+	tree := depth.Tree{Allowlist: []string{"github.com/foo/baz"}, Root: &depth.Pkg{Name: "root", Resolved: true, Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Resolved: true, Depth: 1},
+		{Name: "github.com/foo/bar", Resolved: true, Depth: 1},
+	}}}
+
+	// The violation is returned, but the tree is still written.
+	var b strings.Builder
+	err := writeResult(&b, "root", result{tree: &tree}, &depth.Options{Quiet: true})
+	assert.Equal(t, errPolicyViolation, err)
+	assert.True(t, isCheckFailure(err))
+	assert.Equal(t, "'root': github.com/foo/bar is not in the allowlist\nroot\n  ├ strings\n  └ github.com/foo/bar\n", b.String())
+
+	assert.False(t, isCheckFailure(depth.ErrRootPkgNotResolved))
+	assert.False(t, isCheckFailure(nil))
+}
+
+func Example_writeResultSummaryJSON() {
+	tree := depth.Tree{Root: &depth.Pkg{Name: "root", Deps: []depth.Pkg{
+		{Name: "strings", Internal: true, Depth: 1},
+		{Name: "github.com/foo/bar", Depth: 1, Deps: []depth.Pkg{
+			{Name: "strings", Internal: true, Depth: 2},
+		}},
+	}}}
+	_ = writeResult(os.Stdout, "root", result{tree: &tree}, &depth.Options{SummaryJSON: true})
+	// Output:
+	// {"name":"root","total":2,"internal":1,"external":1,"testing":0,"maxDepth":1,"edges":{"total":3,"test":0,"production":3}}
+}
+
+func Example_writeExplain() {
+	p := depth.Pkg{Name: "root", Deps: []depth.Pkg{
+		{Name: "strings"},
+		{Name: "github.com/foo/bar", Deps: []depth.Pkg{
+			{Name: "strings"},
+		}},
+	}}
+
+	writeExplain(os.Stdout, p, p.ExplainPaths("strings"), false)
+	// Output:
+	// root -> strings
+	// root -> github.com/foo/bar -> strings
+}
+
+func Example_writeExplainTest() {
+	p := depth.Pkg{Name: "root", Deps: []depth.Pkg{
+		{Name: "github.com/foo/bar", Deps: []depth.Pkg{
+			{Name: "strings"},
+		}},
+		{Name: "github.com/foo/baz", Test: true, Deps: []depth.Pkg{
+			{Name: "github.com/foo/bar"},
+		}},
+	}}
+
+	writeExplain(os.Stdout, p, p.ExplainPaths("github.com/foo/bar"), false)
+	// Output:
+	// root -> github.com/foo/bar
+	// root -> github.com/foo/baz [test] -> github.com/foo/bar
+}
+
+func Test_progress(t *testing.T) {
+	var b strings.Builder
+	p := startProgress(&b, false, "strings")
+	p.update(3)
+	p.stop()
+	assert.Equal(t, "resolving strings...\n", b.String())
+
+	var tty strings.Builder
+	p = startProgress(&tty, true, "strings")
+	p.update(3)
+	p.stop()
+	assert.True(t, strings.HasPrefix(tty.String(), "\r\033[Kresolving strings: "))
+	assert.True(t, strings.HasSuffix(tty.String(), "\r\033[K"))
+}
+
+func Test_resolveWithProgress(t *testing.T) {
+	var tr depth.Tree
+	var watched bool
+	r := resolveWithProgress(&tr, "./...", &depth.Options{Watch: true, Recursive: true}, func() error {
+		watched = tr.Progress != nil
+		return tr.ResolveDir(".")
+	})
+	assert.NoError(t, r.err)
+	assert.True(t, watched)
+	assert.Nil(t, tr.Progress)
+
+	err := handlePkgs(&depth.Tree{}, &depth.Options{Watch: true, Parallel: true, PackageNames: []string{"strings"}})
+	assert.EqualError(t, err, "-watch and -watch-files can't be used with -parallel")
+
+	err = handlePkgs(&depth.Tree{}, &depth.Options{WatchFiles: true, Parallel: true, PackageNames: []string{"strings"}})
+	assert.EqualError(t, err, "-watch and -watch-files can't be used with -parallel")
+}
+
+func Test_dirsChecksum(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644))
+	sum := dirsChecksum([]string{dir})
+
+	// Files other than Go files are ignored.
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes\n"), 0644))
+	assert.Equal(t, sum, dirsChecksum([]string{dir}))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nimport _ \"strings\"\n"), 0644))
+	assert.NotEqual(t, sum, dirsChecksum([]string{dir}))
+}
+
+func Example_writeDirect() {
+	var t depth.Tree
+	_ = writeDirect(os.Stdout, &t, []string{"../../testdata/buildtags"})
+
+	t = depth.Tree{BuildTags: []string{"sometag"}}
+	_ = writeDirect(os.Stdout, &t, []string{"../../testdata/buildtags", "../../testdata/loc"})
+	// Output:
+	// strings
+	// ../../testdata/buildtags:
+	//   net/url
+	//   strings
+	// ../../testdata/loc:
+	//   strings
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/adapap/depth"
+)
+
+// defaultFormat is the name of the format used when none is selected.
+const defaultFormat = "tree"
+
+// formatters are the output formats available with -format, keyed by name. Each is created
+// with the Options customizing its output.
+var formatters = map[string]func(options *depth.Options) depth.Formatter{
+	"tree":      func(options *depth.Options) depth.Formatter { return treeFormatter{options} },
+	"json":      func(options *depth.Options) depth.Formatter { return jsonFormatter{options} },
+	"graphml":   func(*depth.Options) depth.Formatter { return depth.NewGraphMLFormatter() },
+	"markdown":  func(*depth.Options) depth.Formatter { return depth.NewMarkdownFormatter() },
+	"svg":       func(*depth.Options) depth.Formatter { return depth.NewSVGFormatter() },
+	"adjacency": func(*depth.Options) depth.Formatter { return depth.NewAdjacencyFormatter() },
+	"dot":       func(*depth.Options) depth.Formatter { return depth.NewDOTFormatter(depth.DOTOptions{}) },
+}
+
+// formatName returns the name of the format selected by the Options, including through the
+// -json, -graphml, -markdown, -svg, -adjacency and -dot aliases.
+func formatName(options *depth.Options) string {
+	switch {
+	case options.Format != "":
+		return options.Format
+	case options.OutputJSON:
+		return "json"
+	case options.OutputGraphML:
+		return "graphml"
+	case options.OutputMarkdown:
+		return "markdown"
+	case options.OutputSVG:
+		return "svg"
+	case options.OutputAdjacency:
+		return "adjacency"
+	case options.OutputDOT:
+		return "dot"
+	}
+	return defaultFormat
+}
+
+// newFormatter returns the Formatter of the format selected by the Options.
+func newFormatter(options *depth.Options) (depth.Formatter, error) {
+	name := formatName(options)
+	fn, ok := formatters[name]
+	if !ok {
+		names := make([]string, 0, len(formatters))
+		for name := range formatters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown format %q, expected one of: %s", name, strings.Join(names, ", "))
+	}
+
+	f := fn(options)
+	if options.Template != "" {
+		if name != defaultFormat {
+			return nil, fmt.Errorf("a template can only be used with the %s format, not %s", defaultFormat, name)
+		}
+		tmpl, err := template.New("template").Parse(options.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		f = templateFormatter{tmpl, options}
+	}
+	if options.RootLabel != "" {
+		f = rootLabelFormatter{f, options.RootLabel}
+	}
+	return f, nil
+}
+
+// rootLabelFormatter shows the root Pkg named by its label, rather than its import path, in
+// the output of the Formatter it wraps.
+type rootLabelFormatter struct {
+	depth.Formatter
+	label string
+}
+
+func (f rootLabelFormatter) Format(w io.Writer, root *depth.Pkg) error {
+	// Only the copy is renamed, and its dependencies are shared since they are unchanged.
+	labeled := *root
+	labeled.Name = f.label
+	return f.Formatter.Format(w, &labeled)
+}
+
+// treeFormatter writes the tree of dependencies as indented text, followed by a summary
+// unless the Options are Quiet.
+type treeFormatter struct {
+	options *depth.Options
+}
+
+func (f treeFormatter) Format(w io.Writer, root *depth.Pkg) error {
+	writePkg(w, *root, f.options)
+	if !f.options.Quiet {
+		writePkgSummary(w, *root, f.options)
+	}
+	return nil
+}
+
+// templateFormatter writes each Pkg in the tree of dependencies, depth first, as rendered by
+// the template, followed by a summary unless the Options are Quiet.
+type templateFormatter struct {
+	tmpl    *template.Template
+	options *depth.Options
+}
+
+func (f templateFormatter) Format(w io.Writer, root *depth.Pkg) error {
+	// Placeholders of omitted dependencies only pretend to have the fields of a Pkg.
+	root = root.WithoutOmitted()
+	if err := writePkgTemplate(w, *root, f.tmpl); err != nil {
+		return err
+	}
+	if !f.options.Quiet {
+		writePkgSummary(w, *root, f.options)
+	}
+	return nil
+}
+
+func writePkgTemplate(w io.Writer, p depth.Pkg, tmpl *template.Template) error {
+	if err := tmpl.Execute(w, p); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	for _, d := range p.Deps {
+		if err := writePkgTemplate(w, d, tmpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFormatter writes the dependencies as JSON, either nested, optionally with IDs, compact
+// or flat, and optionally wrapped in the versioned envelope.
+type jsonFormatter struct {
+	options *depth.Options
+}
+
+func (f jsonFormatter) Format(w io.Writer, root *depth.Pkg) error {
+	// Placeholders of omitted dependencies would appear to be packages.
+	root = root.WithoutOmitted()
+	if !f.options.JSONCompact && !f.options.JSONEnvelope && !f.options.JSONIDs && !f.options.JSONFlat {
+		return depth.NewJSONFormatter().Format(w, root)
+	}
+
+	var v any = *root
+	if f.options.JSONFlat {
+		v = newFlatJSON(*root)
+	} else if f.options.JSONCompact {
+		v = newCompactJSON(*root)
+	} else if f.options.JSONIDs {
+		v = newIDJSON(*root)
+	}
+	if f.options.JSONEnvelope {
+		v = jsonEnvelope{Version: jsonVersion, Root: v, Stats: root.Stats()}
+	}
+	return writeJSON(w, v)
+}
+
+// listFormatter writes the names of the packages listed from the tree of dependencies, one per
+// line, or as a JSON array.
+type listFormatter struct {
+	list func(root *depth.Pkg) ([]string, error)
+	json bool
+}
+
+// newListFormatter returns the listFormatter of the names listed by the function provided, in
+// the format selected by the Options, which must be either the tree or JSON.
+func newListFormatter(options *depth.Options, list func(root *depth.Pkg) ([]string, error)) (depth.Formatter, error) {
+	name := formatName(options)
+	if options.Template != "" {
+		return nil, errors.New("a template can't be used to list packages")
+	} else if name != defaultFormat && name != "json" {
+		return nil, fmt.Errorf("packages can only be listed in the %s or json format, not %s", defaultFormat, name)
+	}
+	return listFormatter{list, name == "json"}, nil
+}
+
+func (f listFormatter) Format(w io.Writer, root *depth.Pkg) error {
+	names, err := f.list(root)
+	if err != nil {
+		return err
+	}
+	if f.json {
+		// An empty list is still an array, rather than null.
+		if names == nil {
+			names = []string{}
+		}
+		return writeJSON(w, names)
+	}
+	for _, name := range names {
+		fmt.Fprintln(w, name)
+	}
+	return nil
+}
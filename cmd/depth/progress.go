@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often the line written by a progress is updated.
+const progressInterval = 100 * time.Millisecond
+
+// progress reports the number of packages resolved while a package is, for -watch.
+// On a terminal, a single line is rewritten with the count and time elapsed, and is cleared
+// once resolution completes. Otherwise, a static message is written once.
+type progress struct {
+	w     io.Writer
+	tty   bool
+	pkg   string
+	start time.Time
+
+	resolved atomic.Int64
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// startProgress starts reporting the progress of resolving the package provided to w.
+func startProgress(w io.Writer, tty bool, pkg string) *progress {
+	p := &progress{w: w, tty: tty, pkg: pkg, start: time.Now(), done: make(chan struct{})}
+	if !tty {
+		fmt.Fprintf(w, "resolving %s...\n", pkg)
+		return p
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			p.writeLine()
+			select {
+			case <-ticker.C:
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// update records the number of packages resolved so far. It's safe to call while the line
+// is written, and can be used as the Progress of a Tree.
+func (p *progress) update(resolved int) {
+	p.resolved.Store(int64(resolved))
+}
+
+// stop stops reporting progress, clearing the line written on a terminal.
+func (p *progress) stop() {
+	close(p.done)
+	p.wg.Wait()
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+}
+
+func (p *progress) writeLine() {
+	elapsed := time.Since(p.start).Truncate(100 * time.Millisecond)
+	fmt.Fprintf(p.w, "\r\033[Kresolving %s: %d packages (%s)", p.pkg, p.resolved.Load(), elapsed)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adapap/depth"
+)
+
+// watchInterval is how often the source directories are checked for changes with -watch-files.
+const watchInterval = time.Second
+
+// watchPkgs resolves and writes each of the packages named by the Options, and then does so
+// again each time the Go files within the source directories of their trees change. Since
+// it runs until interrupted, errors are written with the output rather than returned.
+func watchPkgs(t *depth.Tree, options *depth.Options) error {
+	for {
+		var dirs []string
+		for _, pkg := range options.PackageNames {
+			// Each resolution starts from scratch, so the imports cached before a file
+			// changed aren't reused.
+			t.Reset()
+			_ = writeResult(os.Stdout, pkg, resolvePkg(t, pkg, options), options)
+			dirs = append(dirs, t.SourceDirs()...)
+		}
+
+		fmt.Fprintf(os.Stderr, "watching %d directories for changes...\n", len(dirs))
+		for sum := dirsChecksum(dirs); dirsChecksum(dirs) == sum; {
+			time.Sleep(watchInterval)
+		}
+	}
+}
+
+// dirsChecksum returns a checksum of the names, sizes and modification times of the Go files
+// and go.mod files within the directories provided, which changes whenever one is edited,
+// added or removed.
+func dirsChecksum(dirs []string) uint64 {
+	h := fnv.New64a()
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			fmt.Fprintf(h, "%s: %v\n", dir, err)
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || (!strings.HasSuffix(name, ".go") && name != "go.mod") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(h, "%s %d %d\n", filepath.Join(dir, name), info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return h.Sum64()
+}
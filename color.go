@@ -0,0 +1,53 @@
+package depth
+
+import "fmt"
+
+// ColorMode determines whether the text output is colored.
+type ColorMode int
+
+const (
+	// ColorAuto colors the output only when it's written to a terminal.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors the output, even when it's redirected.
+	ColorAlways
+	// ColorNever never colors the output.
+	ColorNever
+)
+
+var colorModeNames = map[ColorMode]string{
+	ColorAuto:   "auto",
+	ColorAlways: "always",
+	ColorNever:  "never",
+}
+
+// String returns the name of the ColorMode, as accepted by Set.
+func (c ColorMode) String() string {
+	if name, ok := colorModeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("ColorMode(%d)", int(c))
+}
+
+// Set parses the name of a ColorMode, allowing it to be used as a flag.Value.
+func (c *ColorMode) Set(name string) error {
+	for mode, n := range colorModeNames {
+		if n == name {
+			*c = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown color mode %q", name)
+}
+
+// Enabled reports whether output should be colored in the ColorMode, given whether it's
+// written to a terminal.
+func (c ColorMode) Enabled(tty bool) bool {
+	switch c {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return tty
+	}
+}
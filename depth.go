@@ -21,6 +21,7 @@
 //		t := depth.Tree {
 //	 	ResolveInternal: true,
 //	  	ResolveTest: true,
+//	  	ResolveXTest: true,
 //	  	MaxDepth: 10,
 //		}
 //		err := t.Resolve("strings")
@@ -30,8 +31,15 @@ import (
 	"fmt"
 	"errors"
 	"go/build"
+	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -45,6 +53,13 @@ var _ = assert.AnError
 // typically because it does not exist.
 var ErrRootPkgNotResolved = errors.New("unable to resolve root package")
 
+// ErrTimeout is returned when the Tree's Timeout elapses before resolution completes.
+// The Root is still populated, with any unexpanded branches marked as Truncated.
+var ErrTimeout = errors.New("timed out resolving dependencies, tree is incomplete")
+
+// ErrMaxPackages is returned when the Tree's MaxPackages is reached before resolution completes.
+var ErrMaxPackages = errors.New("reached the maximum number of packages, tree is incomplete")
+
 // Importer defines a type that can import a package and return its details.
 type Importer interface {
 	Import(name, srcDir string, im build.ImportMode) (*build.Package, error)
@@ -52,33 +67,313 @@ type Importer interface {
 
 // Tree represents the top level of a Pkg and the configuration used to
 // initialize and represent its contents.
+//
+// While resolving, the packages at each level of the tree are imported concurrently, so the
+// Importer and InternalFunc must be safe for concurrent use. All other state shared between
+// those imports is guarded by the Tree. A single Tree must not be resolved, reset or modified
+// from several goroutines at once, but each Clone of it may be resolved concurrently, including
+// with a shared Importer such as a CachingImporter.
 type Tree struct {
-	Root  *Pkg
+	Root *Pkg
+	// Mutex guards the imports seen and the modules found while resolving, which are shared
+	// between the concurrent imports. It's used by the Tree itself, and callers need not hold it.
 	Mutex sync.Mutex
 
 	ResolveInternal bool
+	// ResolveTest resolves the imports of the test files within each package, while
+	// ResolveXTest resolves the imports of its external test package (package foo_test).
 	ResolveTest     bool
+	ResolveXTest    bool
 	MaxDepth        int
+	// IncludePatterns and ExcludePatterns limit the dependencies to the packages whose names
+	// contain any of the include patterns, if any are set, and none of the exclude patterns.
+	// They apply equally to test dependencies, and a package filtered out is dropped along
+	// with the dependencies that would have been reached through it.
 	IncludePatterns []string
 	ExcludePatterns []string
 	Importer        Importer
 	Verbose         bool
-	importCache     set.Set[string]
+
+	// Logger, when set, logs each import at debug level, along with the reason that any
+	// package is skipped or left unexpanded, and any error encountered importing it.
+	Logger *slog.Logger
+	// Progress, when set, is called with the number of packages resolved so far as each one is,
+	// in the same breadth-first order as ResolveStream sends them. It's called from a single
+	// goroutine at a time.
+	Progress func(resolved int)
+
+	// MergeTestDeps treats packages imported both by tests and by non-test files as non-test
+	// dependencies marked AlsoTest, rather than counting them as both.
+	MergeTestDeps bool
+
+	// SeparateTestRoots models the external test package of the Root as a distinct
+	// dependency of it, named "foo [test]", holding the XTestImports. Otherwise they are
+	// merged into the dependencies of the Root itself. It has no effect without ResolveXTest.
+	SeparateTestRoots bool
+
+	// InternalFunc, when set, determines whether a Pkg is Internal in place of the default
+	// of treating only standard library packages as internal. The Pkg provided has been
+	// imported, so its Raw details are available. InternalFunc may be called concurrently.
+	//
+	// This changes how packages are sorted and counted, but standard library packages are
+	// still the ones left unexpanded unless ResolveInternal is set.
+	InternalFunc func(p *Pkg) bool
+
+	// ResolveInternalFunc, when set, determines whether the dependencies of each internal
+	// package are resolved in place of ResolveInternal, so that only some are expanded. It's
+	// called for standard library packages, and for those made Internal by the InternalFunc,
+	// but not for the Root, which is always resolved. ResolveInternalFunc may be called
+	// concurrently.
+	ResolveInternalFunc func(p *Pkg) bool
+
+	// HideInternalNoise hides the NoisePackages from the output, as left out by WithoutNoise,
+	// while they're still resolved and counted. PruneNoise leaves them out of the Deps of
+	// every Pkg instead, so they are neither resolved nor counted. NoisePackages defaults to
+	// DefaultNoisePackages if nil.
+	HideInternalNoise bool
+	PruneNoise        bool
+	NoisePackages     []string
+
+	// CgoEnabled overrides the CgoEnabled setting of the build context used by the
+	// default Importer, when non-nil.
+	CgoEnabled *bool
+	// BuildTags are added to the build tags of the build context used by the default
+	// Importer, changing which files, and therefore which imports, are considered.
+	BuildTags []string
+	// GOROOT overrides the GOROOT of the build context used by the default Importer, so the
+	// standard library of another Go toolchain installed there is resolved instead. Its
+	// release tags are taken from the toolchain's VERSION file.
+	GOROOT string
+	// ModuleMode determines whether the default Importer resolves packages using modules or
+	// the GOPATH. By default, modules are used when the working directory is within one.
+	ModuleMode ModuleMode
+	// ImportRetries is the number of times an import failing with a transient error, such as
+	// a network timeout, is retried with exponential backoff, using a RetryingImporter.
+	ImportRetries int
+	// VendorDir, if set, is a vendor directory within which imports are found before being
+	// resolved by the Importer, using a VendorImporter, so the dependencies a vendored build
+	// would compile are analyzed. Relative directories are relative to the working directory.
+	VendorDir string
+	// NoFollowSymlinks disables resolving symlinks in the directories of packages before they
+	// are used to import their dependencies.
+	NoFollowSymlinks bool
+	// Timeout is the maximum amount of time spent resolving before the remaining
+	// packages are left unexpanded. If zero, resolution is not time limited.
+	Timeout time.Duration
+	// FindOnly locates dependencies without reading their source files, as a quick way
+	// to validate that the root package and its direct dependencies exist.
+	//
+	// The root package is still imported in full to discover its imports, but since
+	// no other package is read, transitive dependencies are never resolved.
+	FindOnly bool
+	// StopAtExternal finds packages outside of the standard library and the main module, as
+	// identified by the ModulePrefix, without resolving their dependencies. This shows the
+	// first-party packages along with the external packages they import directly.
+	StopAtExternal bool
+	// OpaquePackages are found without resolving their dependencies, saving the time spent
+	// on packages whose internals are of no interest. Each entry matches names containing
+	// it, or matching it as a glob.
+	OpaquePackages []string
+	// CountLOC sets the LinesOfCode of each Pkg that is imported, which requires reading
+	// all of its Go files. IgnoreVendor leaves vendored packages uncounted.
+	CountLOC     bool
+	IgnoreVendor bool
+	// Allowlist is the set of external packages the Root is permitted to depend on, as
+	// reported by Violations. Each entry matches names containing it, or matching it as a glob.
+	Allowlist []string
+	// Banned is the set of packages the Root must not depend on, directly or transitively,
+	// as reported by BannedFound. Entries match in the same way as those of the Allowlist.
+	Banned []string
+	// ModulePrefix is the path of the main module, containing the working directory. If
+	// empty, each resolution detects it using ParseModule, as reported by MainModule.
+	ModulePrefix string
+	// SortMode determines the order of the Deps of each Pkg.
+	SortMode SortMode
+	// MaxBreadth limits the number of Deps of each Pkg, keeping the first in the order of
+	// the SortMode and replacing the rest with a single Pkg noting how many were omitted.
+	// The Deps are limited before they are imported, so those omitted are never resolved,
+	// and they are ordered by what is known of them beforehand: their import paths. If zero,
+	// the number of Deps is not limited.
+	MaxBreadth int
+	// MaxPackages limits the total number of packages whose dependencies are resolved,
+	// leaving the remaining packages unexpanded and marked Truncated. Unlike MaxDepth and
+	// MaxBreadth, it applies to the tree as a whole. If zero, it is not limited.
+	MaxPackages int
+	// MaxPaths limits the number of paths returned by PathsBetween. If zero, at most 100
+	// are returned.
+	MaxPaths int
+
+	importCache set.Set[string]
+	imports     map[string]*Pkg
+	moduleCache map[string]*Module
+	mainModule  string
+	deadline    time.Time
+	timedOut    atomic.Bool
+	resolved    atomic.Int64
+	limited     atomic.Bool
 }
 
 type Options struct {
 	PackageNames []string
-	OutputJSON   bool
-	ExplainPkg   string
+
+	// Format is the name of the output format, such as "json". OutputJSON, OutputGraphML,
+	// OutputMarkdown, OutputSVG, OutputAdjacency and OutputDOT are aliases of their formats
+	// used when Format is empty.
+	Format         string
+	OutputJSON     bool
+	ExplainPkg     string
+	PathFrom       string
+	PathTo         string
+	CostPkg        string
+	SubtreePkg     string
+	Parallel       bool
+	Recursive      bool
+	MaxConcurrency int
+
+	// OnlyUnresolved and OnlyTest limit the output to the unresolved or test packages,
+	// along with the packages needed to reach them.
+	OnlyUnresolved bool
+	OnlyTest       bool
+
+	// CollapseStdlibInternal replaces the internal packages of the standard library imported
+	// by each package in the output with a single "(stdlib internals)" package.
+	CollapseStdlibInternal bool
+
+	// OutputGraphML outputs the dependencies as a GraphML document.
+	OutputGraphML bool
+	// OutputMarkdown outputs the dependencies as nested Markdown lists linking to their docs.
+	OutputMarkdown bool
+	// OutputSVG outputs the dependencies as an SVG image, laid out by depth.
+	OutputSVG bool
+	// OutputAdjacency outputs a line for each unique package, listing its direct dependencies.
+	OutputAdjacency bool
+	// OutputDOT outputs the dependencies as a Graphviz DOT digraph.
+	OutputDOT bool
+
+	// FanIn annotates each package with the number of packages importing it.
+	FanIn bool
+	// MinDepth annotates each package with the minimum depth at which it appears.
+	MinDepth bool
+	// Height annotates each package with its GraphHeight, the number of levels of dependencies
+	// beneath it. MinHeight limits the output to the packages with at least that many levels,
+	// along with the packages needed to reach them.
+	Height    bool
+	MinHeight int
+
+	// JSONCompact outputs the JSON as a graph of nodes and edges, rather than nested Pkgs,
+	// so that each unique package appears only once.
+	JSONCompact bool
+	// JSONFlat outputs the JSON as an array of the unique packages, each with its minimum depth
+	// and number of importers, rather than nested Pkgs.
+	JSONFlat bool
+	// JSONIDs includes a stable ID for each package in the nested JSON output, derived from
+	// the sorted names of the packages, with each dependency referencing the ID of its parent.
+	JSONIDs bool
+	// SummaryJSON outputs only the Stats of each package, as a line of JSON.
+	SummaryJSON bool
+	// JSONEnvelope wraps the JSON output in a versioned object alongside its Stats.
+	JSONEnvelope bool
+	// ListStdlib outputs only the standard library packages depended on.
+	ListStdlib bool
+	// TopoSort outputs the packages in topological order, each after its dependencies.
+	TopoSort bool
+	// Direct outputs only the packages imported by each package, using ResolveDirect rather
+	// than resolving the whole tree.
+	Direct bool
+	// ListTestOnly outputs only the TestOnlyDeps, the packages only depended on because of tests.
+	ListTestOnly bool
+	// ListLeaves outputs only the packages without dependencies of their own.
+	ListLeaves bool
+	// PreviewMaxDepth outputs only the packages that would be left out by resolving with a
+	// MaxDepth of its value, when non-zero.
+	PreviewMaxDepth int
+	// Histogram outputs the number of unique packages at each depth as a bar chart.
+	Histogram bool
+	// MinGoVersion outputs only the highest Go version declared by the modules depended on.
+	MinGoVersion bool
+	// ListConflicts outputs only the modules seen at more than one version, with their versions.
+	ListConflicts bool
+	// Metrics outputs only the GraphMetrics of the deduplicated dependency graph.
+	Metrics bool
+	// ListFlat outputs only the sorted, unique names of the packages depended on.
+	ListFlat bool
+	// BaselineFile is a list of packages, as output by ListFlat, that the dependencies are
+	// checked against, failing if any are missing from it. If UpdateBaseline is set, the
+	// file is written with the current dependencies instead.
+	BaselineFile   string
+	UpdateBaseline bool
+	// Acyclic fails if there are import cycles among the packages of the main module, listing
+	// them. AcyclicAll does so for cycles among any packages.
+	Acyclic    bool
+	AcyclicAll bool
+	// ListLOC outputs the lines of code of each package as a table, from largest to smallest.
+	ListLOC bool
+
+	// Quiet suppresses the summary and timing lines following the text output.
+	Quiet bool
+	// Watch reports the number of packages imported while each package is resolved.
+	Watch bool
+	// WatchFiles resolves and outputs the packages again each time the Go files within the
+	// SourceDirs of their trees change, until interrupted.
+	WatchFiles bool
+	// CountExternal adds a summary line counting only third-party (non-stdlib) packages.
+	CountExternal bool
+	// CountPrefixes adds a summary line for each prefix, counting the packages starting with it.
+	CountPrefixes []string
+
+	// TrimPrefix is stripped from the names of packages in the text output, leaving them
+	// relative to it. If TrimRootPrefix is set, the prefix is derived from the module (or name)
+	// of the root package instead.
+	TrimPrefix     string
+	TrimRootPrefix bool
+
+	// ShowPositions annotates each package in the text and explain output with the positions
+	// at which it is imported.
+	ShowPositions bool
+
+	// HighlightPatterns marks packages matching any of the patterns in the text output.
+	HighlightPatterns []string
+
+	// Color determines whether packages in the text output are colored by their kind:
+	// internal, external, unresolved or test.
+	Color ColorMode
+
+	// RootLabel, if set, is shown in place of the name of the root package in the output,
+	// such as for the synthetic root of a directory. The name is still used for resolving.
+	RootLabel string
+
+	// Template, if set, replaces the text output of each package with the text/template
+	// provided, rendered with the Pkg, such as "{{.Name}} ({{.Depth}})".
+	Template string
 }
 
 // Resolve recursively finds all dependencies for the root Pkg name provided,
 // and the packages it depends on.
 func (t *Tree) Resolve(name string) error {
+	return t.resolve(name, nil)
+}
+
+// resolve resolves the package named as Resolve does, calling visit with each package once
+// it has been imported, as Pkg.resolve does.
+func (t *Tree) resolve(name string, visit func(p *Pkg) bool) error {
+	if err := ValidateImportPath(name); err != nil {
+		return err
+	}
+
 	pwd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
+	if t.GOROOT != "" {
+		if err := checkGOROOT(t.GOROOT); err != nil {
+			return err
+		}
+	}
+
+	// Relative packages are named by their canonical import path, when it can be found.
+	if build.IsLocalImport(name) {
+		name = t.canonicalImportPath(name, pwd)
+	}
 
 	t.Root = &Pkg{
 		Name:   name,
@@ -86,24 +381,292 @@ func (t *Tree) Resolve(name string) error {
 		SrcDir: pwd,
 		Test:   false,
 	}
+	return t.resolveRoot(pwd, visit)
+}
 
-	// Reset the import cache each time to ensure a reused Tree doesn't
-	// reuse the same cache.
-	t.importCache = nil
+// ResolveDir resolves every package within the directory provided, and the directories
+// beneath it, as the dependencies of a single synthetic Root named like the "dir/..."
+// pattern matching them. Packages depended on by several of them are only expanded once,
+// so the tree shows what the directory depends on collectively. Directories are walked in
+// the same way as by ExpandPatterns, skipping testdata, vendor and hidden directories.
+func (t *Tree) ResolveDir(dir string) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if t.GOROOT != "" {
+		if err := checkGOROOT(t.GOROOT); err != nil {
+			return err
+		}
+	}
+
+	// Directories are walked through a local pattern, so that the packages found are local.
+	base := filepath.ToSlash(filepath.Clean(dir))
+	if filepath.IsAbs(dir) {
+		rel, err := filepath.Rel(pwd, dir)
+		if err != nil {
+			return err
+		}
+		base = filepath.ToSlash(rel)
+	}
+	if !build.IsLocalImport(base) {
+		base = "./" + base
+	}
+
+	names, err := expandPattern(t.BuildContext(), base+"/...")
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no Go packages found in %s", dir)
+	}
+	for idx, name := range names {
+		names[idx] = t.canonicalImportPath(name, pwd)
+	}
+
+	t.Root = &Pkg{
+		Name:      base + "/...",
+		Tree:      t,
+		SrcDir:    pwd,
+		Raw:       &build.Package{Dir: pwd, Imports: names},
+		synthetic: true,
+	}
+	return t.resolveRoot(pwd, nil)
+}
 
-	// Allow custom importers, but use a caching importer if none is provided.
+// ResolveDirect returns the sorted import paths of the packages imported by the package
+// named, including those imported by its tests when the Tree resolves them, without resolving
+// any of them. Only the package itself is imported, which is much faster than Resolve when
+// only the first level of the tree is needed. The IncludePatterns, ExcludePatterns and
+// NoisePackages of the Tree apply as they would to the dependencies of the Root, but the Root
+// itself is left untouched.
+func (t *Tree) ResolveDirect(name string) ([]string, error) {
+	if err := ValidateImportPath(name); err != nil {
+		return nil, err
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	if t.GOROOT != "" {
+		if err := checkGOROOT(t.GOROOT); err != nil {
+			return nil, err
+		}
+	}
+
+	pkg, err := t.importer().Import(name, pwd, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRootPkgNotResolved, err)
+	}
+
+	imports := [][]string{pkg.Imports}
+	if t.ResolveTest {
+		imports = append(imports, pkg.TestImports)
+	}
+	if t.ResolveXTest {
+		imports = append(imports, pkg.XTestImports)
+	}
+
+	unique := make(map[string]struct{})
+	for _, imp := range slices.Concat(imports...) {
+		if imp == "C" || imp == pkg.ImportPath || t.isNoise(imp) || !MatchesPatterns(imp, t.IncludePatterns, t.ExcludePatterns) {
+			continue
+		}
+		unique[imp] = struct{}{}
+	}
+	return slices.Sorted(maps.Keys(unique)), nil
+}
+
+// importer returns the Importer used to resolve the Tree, setting its Importer to a
+// CachingImporter using the BuildContext of the Tree if none is provided, finding imports
+// within the VendorDir of the Tree first, and retrying imports if the Tree has ImportRetries.
+func (t *Tree) importer() Importer {
 	if t.Importer == nil {
-		t.Importer = NewCachingImporter()
+		importer := NewCachingImporter()
+		importer.Context = t.BuildContext()
+		t.Importer = importer
 	}
 
-	t.Root.Resolve(t.Importer)
+	importer := t.Importer
+	if t.VendorDir != "" {
+		importer = NewVendorImporter(importer, t.VendorDir)
+	}
+	if t.ImportRetries > 0 {
+		return NewRetryingImporter(importer, t.ImportRetries)
+	}
+	return importer
+}
+
+// resolveRoot resolves the Root of the Tree, and the packages it depends on, from the
+// working directory pwd, calling visit with each package as Pkg.resolve does.
+func (t *Tree) resolveRoot(pwd string, visit func(p *Pkg) bool) error {
+	// Reset the import cache each time to ensure a reused Tree doesn't
+	// reuse the same cache.
+	t.resetState()
+	t.mainModule = t.modulePrefix(pwd)
+	if t.Timeout > 0 {
+		t.deadline = time.Now().Add(t.Timeout)
+	}
+
+	t.Root.resolve(t.importer(), visit)
 	if !t.Root.Resolved {
 		return ErrRootPkgNotResolved
 	}
+	t.Root.markPotentiallyExported()
+	if t.timedOut.Load() {
+		return ErrTimeout
+	}
+	if t.limited.Load() {
+		return ErrMaxPackages
+	}
 
 	return nil
 }
 
+// Reset clears the Root and all state from previous resolutions of the Tree, including the
+// cache of a CachingImporter, so the next Resolve starts from scratch. The rest of the
+// Tree's configuration is kept.
+func (t *Tree) Reset() {
+	t.Root = nil
+	t.resetState()
+
+	if importer, ok := t.Importer.(*CachingImporter); ok {
+		importer.ClearCache()
+	}
+}
+
+// resetState clears the state of a previous resolution of the Tree.
+func (t *Tree) resetState() {
+	t.importCache = nil
+	t.imports = nil
+	t.moduleCache = nil
+	t.mainModule = ""
+
+	t.deadline = time.Time{}
+	t.timedOut.Store(false)
+	t.resolved.Store(0)
+	t.limited.Store(false)
+}
+
+// MainModule returns the path of the main module of the last resolution of the Tree: the
+// ModulePrefix if set, and otherwise the module containing the working directory.
+func (t *Tree) MainModule() string {
+	if t.mainModule == "" {
+		return t.ModulePrefix
+	}
+	return t.mainModule
+}
+
+// modulePrefix returns the path of the main module when resolving from the working
+// directory pwd, which is the ModulePrefix if set.
+func (t *Tree) modulePrefix(pwd string) string {
+	if t.ModulePrefix != "" {
+		return t.ModulePrefix
+	}
+	if mod, err := ParseModule(pwd); err == nil {
+		return mod.Path
+	}
+	return ""
+}
+
+// canonicalImportPath returns the import path of the package in the directory named by the
+// relative path provided, from the working directory pwd. The import path is derived from
+// the module containing the directory, and is only returned if importing it with the Importer
+// of the Tree finds the same directory. Otherwise, the relative path is returned.
+func (t *Tree) canonicalImportPath(name, pwd string) string {
+	dir := filepath.Join(pwd, name)
+	mod := findModule(dir)
+	if mod == nil || mod.Version != "" {
+		return name
+	}
+
+	rel, err := filepath.Rel(mod.Dir, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return name
+	}
+	path := mod.Path
+	if rel != "." {
+		path += "/" + filepath.ToSlash(rel)
+	}
+
+	pkg, err := t.importer().Import(path, pwd, build.FindOnly)
+	if err != nil || pkg.Dir != dir {
+		return name
+	}
+	return path
+}
+
+// Clone returns a new Tree with the same configuration as t, but none of its
+// resolution state. The Importer is shared, so a caching Importer continues to
+// benefit every clone.
+func (t *Tree) Clone() *Tree {
+	return &Tree{
+		ResolveInternal: t.ResolveInternal,
+		ResolveTest:     t.ResolveTest,
+		ResolveXTest:    t.ResolveXTest,
+		MergeTestDeps:   t.MergeTestDeps,
+		MaxDepth:        t.MaxDepth,
+		IncludePatterns: t.IncludePatterns,
+		ExcludePatterns: t.ExcludePatterns,
+		Importer:        t.Importer,
+		Verbose:         t.Verbose,
+		Logger:          t.Logger,
+		Progress:        t.Progress,
+		InternalFunc:    t.InternalFunc,
+		CgoEnabled:      t.CgoEnabled,
+		BuildTags:       t.BuildTags,
+		GOROOT:          t.GOROOT,
+		ModuleMode:      t.ModuleMode,
+		Timeout:         t.Timeout,
+		SortMode:        t.SortMode,
+		ModulePrefix:    t.ModulePrefix,
+		FindOnly:        t.FindOnly,
+		StopAtExternal:  t.StopAtExternal,
+		OpaquePackages:  t.OpaquePackages,
+		CountLOC:        t.CountLOC,
+		IgnoreVendor:    t.IgnoreVendor,
+		Allowlist:       t.Allowlist,
+		Banned:          t.Banned,
+		MaxBreadth:      t.MaxBreadth,
+		MaxPackages:     t.MaxPackages,
+		ImportRetries:   t.ImportRetries,
+		VendorDir:       t.VendorDir,
+		MaxPaths:        t.MaxPaths,
+
+		NoFollowSymlinks:    t.NoFollowSymlinks,
+		SeparateTestRoots:   t.SeparateTestRoots,
+		HideInternalNoise:   t.HideInternalNoise,
+		PruneNoise:          t.PruneNoise,
+		NoisePackages:       t.NoisePackages,
+		ResolveInternalFunc: t.ResolveInternalFunc,
+	}
+}
+
+// BuildContext returns the build.Context used by the default Importer, which is build.Default
+// with the CgoEnabled, BuildTags, GOROOT and ModuleMode overrides configured on the Tree
+// applied.
+func (t *Tree) BuildContext() *build.Context {
+	ctx := build.Default
+	if t.CgoEnabled != nil {
+		ctx.CgoEnabled = *t.CgoEnabled
+	}
+	if t.GOROOT != "" {
+		ctx.GOROOT = filepath.Clean(t.GOROOT)
+		if tags, ok := releaseTags(ctx.GOROOT); ok {
+			ctx.ReleaseTags = tags
+		}
+	}
+	ctx.BuildTags = append(slices.Clone(ctx.BuildTags), t.BuildTags...)
+
+	// The go command, and with it module mode, is only used by go/build when none of the
+	// file system callbacks are set, so setting one restricts it to the GOPATH.
+	if !t.usesModules() {
+		ctx.JoinPath = filepath.Join
+	}
+	return &ctx
+}
+
 // shouldResolveInternal determines if internal packages should be further resolved beyond the
 // current parent.
 //
@@ -112,11 +675,14 @@ func (t *Tree) Resolve(name string) error {
 // dependencies, and it is passed as the parent here, false may be returned and its internal
 // dependencies will not be resolved.
 func (t *Tree) shouldResolveInternal(parent *Pkg) bool {
-	if t.ResolveInternal {
+	if parent == t.Root {
 		return true
 	}
+	if t.ResolveInternalFunc != nil {
+		return t.ResolveInternalFunc(parent)
+	}
 
-	return parent == t.Root
+	return t.ResolveInternal
 }
 
 // isAtMaxDepth returns true when the depth of the Pkg provided is at or beyond the maximum
@@ -131,6 +697,32 @@ func (t *Tree) isAtMaxDepth(p *Pkg) bool {
 	return p.depth() >= t.MaxDepth
 }
 
+// isPastDeadline returns true if the Timeout of the tree has elapsed, recording that the
+// resolution timed out.
+//
+// If the Tree has no Timeout, false is always returned.
+func (t *Tree) isPastDeadline() bool {
+	if t.deadline.IsZero() || time.Now().Before(t.deadline) {
+		return false
+	}
+
+	t.timedOut.Store(true)
+	return true
+}
+
+// isPastMaxPackages counts a package to be resolved, and returns true if doing so exceeds
+// the MaxPackages of the tree, recording that the limit was reached.
+//
+// If the Tree has no MaxPackages, false is always returned.
+func (t *Tree) isPastMaxPackages() bool {
+	if t.MaxPackages == 0 || t.resolved.Add(1) <= int64(t.MaxPackages) {
+		return false
+	}
+
+	t.limited.Store(true)
+	return true
+}
+
 // hasSeenImport returns true if the import name provided has already been seen within the tree.
 // This function only returns false for a name once.
 func (t *Tree) hasSeenImport(name string) bool {
@@ -150,3 +742,34 @@ func (t *Tree) hasSeenImport(name string) bool {
 	}
 	return false
 }
+
+// memoize records the Pkg imported by the name provided, so that later occurrences of the
+// package reuse it rather than importing it again. The Pkg is copied without its Deps, as
+// those of the original are still being resolved.
+func (t *Tree) memoize(name string, p *Pkg) {
+	c := *p
+	c.Deps, c.Parent = nil, nil
+
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if t.imports == nil {
+		t.imports = make(map[string]*Pkg)
+	}
+	t.imports[name] = &c
+}
+
+// memoized returns the Pkg recorded by memoize for the name provided, or nil.
+func (t *Tree) memoized(name string) *Pkg {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	return t.imports[name]
+}
+
+// debug logs the message and attributes provided at debug level, if the Tree has a Logger.
+func (t *Tree) debug(msg string, args ...any) {
+	if t.Logger != nil {
+		t.Logger.Debug(msg, args...)
+	}
+}
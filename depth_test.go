@@ -1,8 +1,17 @@
 package depth
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go/build"
+	"log/slog"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -105,3 +114,722 @@ func TestTree_hasSeenImport(t *testing.T) {
 		t.Fatalf("Expected true to be returned after the import name has been seen, got=false")
 	}
 }
+
+func TestTree_ResolveTimeout(t *testing.T) {
+	var modes []build.ImportMode
+	tr := Tree{
+		Timeout: time.Nanosecond,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			modes = append(modes, im)
+			if im == build.FindOnly {
+				return &build.Package{ImportPath: name}, nil
+			}
+			return &build.Package{ImportPath: name, Imports: []string{"dep"}}, nil
+		}},
+	}
+
+	if err := tr.Resolve("name"); err != ErrTimeout {
+		t.Fatalf("Unexpected error, expected=%v, got=%v", ErrTimeout, err)
+	}
+	assert.True(t, tr.Root.Resolved)
+	assert.True(t, tr.Root.Truncated)
+	assert.Equal(t, []build.ImportMode{build.FindOnly}, modes)
+}
+
+func TestTree_ResolveDeterministic(t *testing.T) {
+	graph := map[string][]string{
+		"root": {"a", "b"},
+		"a":    {"x"},
+		"x":    {"c"},
+		"b":    {"c"},
+		"c":    {"d"},
+	}
+
+	for i := 0; i < 10; i++ {
+		tr := Tree{
+			Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+				if im == build.FindOnly {
+					return &build.Package{ImportPath: name}, nil
+				}
+				return &build.Package{ImportPath: name, Imports: graph[name]}, nil
+			}},
+		}
+		assert.NoError(t, tr.Resolve("root"))
+
+		// The shallowest occurrence of c is the one that is expanded.
+		b := tr.Root.Deps[1]
+		assert.Equal(t, "c", b.Deps[0].Name)
+		assert.Len(t, b.Deps[0].Deps, 1)
+
+		x := tr.Root.Deps[0].Deps[0]
+		assert.Equal(t, "c", x.Deps[0].Name)
+		assert.Len(t, x.Deps[0].Deps, 0)
+	}
+}
+
+func TestTree_ResolveInternalFunc(t *testing.T) {
+	tr := Tree{
+		InternalFunc: func(p *Pkg) bool {
+			return strings.HasPrefix(p.Name, "github.com/org/")
+		},
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			if name == "github.com/org/root" {
+				return &build.Package{ImportPath: name, Imports: []string{"github.com/org/lib", "github.com/other/lib", "strings"}}, nil
+			}
+			return &build.Package{ImportPath: name, Goroot: name == "strings"}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+
+	internal := make(map[string]bool)
+	for _, dep := range tr.Root.Deps {
+		internal[dep.Name] = dep.Internal
+	}
+	assert.Equal(t, map[string]bool{"github.com/org/lib": true, "github.com/other/lib": false, "strings": false}, internal)
+
+	// The org's packages are sorted first, and stdlib packages are still reported as such.
+	assert.Equal(t, "github.com/org/lib", tr.Root.Deps[0].Name)
+	assert.Equal(t, []string{"strings"}, tr.StdlibDeps())
+}
+
+func TestTree_ResolveInternalFuncSelective(t *testing.T) {
+	imports := map[string][]string{
+		"github.com/org/root":            {"github.com/org/root/internal/a", "strings"},
+		"github.com/org/root/internal/a": {"github.com/org/root/internal/b"},
+		"github.com/org/root/internal/b": {"errors"},
+		"strings":                        {"errors"},
+	}
+	tr := Tree{
+		InternalFunc: func(p *Pkg) bool {
+			return p.Raw.Goroot || strings.Contains(p.Name, "/internal/")
+		},
+		ResolveInternalFunc: func(p *Pkg) bool {
+			return strings.HasPrefix(p.Name, "github.com/org/root/")
+		},
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			return &build.Package{ImportPath: name, Goroot: !strings.Contains(name, "."), Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+
+	// The module's own internal packages are expanded, while the stdlib's are not.
+	expanded := make(map[string]bool)
+	tr.Root.Walk(func(p *Pkg, depth int) bool {
+		expanded[p.Name] = len(p.Deps) > 0
+		return true
+	})
+	assert.Equal(t, map[string]bool{
+		"github.com/org/root":            true,
+		"github.com/org/root/internal/a": true,
+		"github.com/org/root/internal/b": true,
+		"strings":                        false,
+		"errors":                         false,
+	}, expanded)
+}
+
+func TestTree_Reset(t *testing.T) {
+	c := NewCachingImporter()
+	tr := Tree{Importer: c}
+	assert.NoError(t, tr.Resolve("strings"))
+	assert.NotEmpty(t, c.cache)
+
+	tr.Reset()
+	assert.Nil(t, tr.Root)
+	assert.Nil(t, tr.importCache)
+	assert.Nil(t, tr.imports)
+	assert.Nil(t, tr.moduleCache)
+	assert.Empty(t, c.cache)
+	assert.Same(t, c, tr.Importer)
+
+	assert.NoError(t, tr.Resolve("strings"))
+	assert.NotNil(t, tr.Root)
+}
+
+func TestTree_ResolveDirect(t *testing.T) {
+	tr := Tree{
+		ResolveTest: true,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			if name == "root" {
+				return &build.Package{ImportPath: name, Imports: []string{"a"}, TestImports: []string{"b"}}, nil
+			}
+			return &build.Package{ImportPath: name, Imports: []string{"c"}}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	direct := make(map[string]bool)
+	tr.Root.Walk(func(p *Pkg, depth int) bool {
+		direct[p.Name] = direct[p.Name] || p.Direct
+		return true
+	})
+	assert.Equal(t, map[string]bool{"root": false, "a": true, "b": false, "c": false}, direct)
+}
+
+func TestTree_ResolveDirectImports(t *testing.T) {
+	var imported []string
+	tr := Tree{
+		ResolveTest:     true,
+		ExcludePatterns: []string{"x"},
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imported = append(imported, name)
+			return &build.Package{ImportPath: name, Imports: []string{"c", "a", "C", "x"}, TestImports: []string{"b", "a", "root"}}, nil
+		}},
+	}
+
+	deps, err := tr.ResolveDirect("root")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, deps)
+	assert.Nil(t, tr.Root)
+
+	// Only the package itself is imported.
+	assert.Equal(t, []string{"root"}, imported)
+
+	tr.Importer = MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return nil, errors.New("not found")
+	}}
+	_, err = tr.ResolveDirect("root")
+	assert.ErrorIs(t, err, ErrRootPkgNotResolved)
+}
+
+func TestTree_Leaves(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.Leaves())
+
+	imports := map[string][]string{
+		"root": {"a", "b", "strings"},
+		"a":    {"b", "c"},
+		"b":    {},
+		"c":    {"d"},
+	}
+	tr = Tree{
+		MaxDepth: 2,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			return &build.Package{ImportPath: name, Goroot: name == "strings", Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// strings is not expanded as a stdlib package, and c is cut off by the MaxDepth.
+	assert.Equal(t, []string{"b"}, tr.Leaves())
+}
+
+func TestTree_ResolveMergeTestDeps(t *testing.T) {
+	imports := map[string]*build.Package{
+		"root": {Imports: []string{"a"}, TestImports: []string{"b", "c"}},
+		"a":    {Imports: []string{"b"}},
+		"b":    {Imports: []string{"d"}},
+	}
+	importer := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		var pkg build.Package
+		if imports[name] != nil {
+			pkg = *imports[name]
+		}
+		pkg.ImportPath = name
+		return &pkg, nil
+	}}
+
+	for _, merge := range []bool{false, true} {
+		tr := Tree{ResolveTest: true, MergeTestDeps: merge, Importer: importer}
+		assert.NoError(t, tr.Resolve("root"))
+
+		var b []Pkg
+		tr.Root.Walk(func(p *Pkg, depth int) bool {
+			if p.Name == "b" {
+				b = append(b, *p)
+			}
+			return true
+		})
+		if assert.Len(t, b, 2) {
+			// b is imported by the tests of root and by a, and the expanded subtree is kept.
+			assert.Equal(t, !merge, b[0].Test || b[1].Test)
+			assert.Equal(t, merge, b[0].AlsoTest && b[1].AlsoTest)
+			assert.Equal(t, 1, len(b[0].Deps)+len(b[1].Deps))
+		}
+	}
+
+	tr := Tree{ResolveTest: true, MergeTestDeps: true, Importer: importer}
+	assert.NoError(t, tr.Resolve("root"))
+	assert.Equal(t, Stats{Total: 4, External: 4, Testing: 1, MaxDepth: 2}, tr.Stats())
+}
+
+func TestTree_ResolveMemoized(t *testing.T) {
+	graph := map[string][]string{
+		"root": {"a", "b"},
+		"a":    {"c"},
+		"b":    {"c"},
+		"c":    {"d"},
+	}
+
+	var mu sync.Mutex
+	imports := make(map[string]int)
+	tr := Tree{
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			imports[name]++
+			return &build.Package{ImportPath: name, Imports: graph[name], CgoFiles: []string{"c.go"}}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// Each package is imported once, with its repeated occurrences reusing the first.
+	assert.Equal(t, map[string]int{"root": 1, "a": 1, "b": 1, "c": 1, "d": 1}, imports)
+
+	first, repeated := &tr.Root.Deps[0].Deps[0], &tr.Root.Deps[1].Deps[0]
+	assert.Len(t, first.Deps, 1)
+	assert.Empty(t, repeated.Deps)
+	assert.Same(t, first.Raw, repeated.Raw)
+	assert.True(t, repeated.Resolved)
+	assert.True(t, repeated.UsesCgo)
+	assert.Same(t, first, tr.Root.Find("c"))
+}
+
+func TestTree_ResolveModulePrefix(t *testing.T) {
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return &build.Package{ImportPath: name}, nil
+	}}}
+	assert.NoError(t, tr.Resolve("name"))
+	assert.Equal(t, "github.com/adapap/depth", tr.MainModule())
+	assert.Empty(t, tr.ModulePrefix)
+
+	// An explicit ModulePrefix is used.
+	tr.ModulePrefix = "github.com/foo/bar"
+	assert.NoError(t, tr.Resolve("name"))
+	assert.Equal(t, "github.com/foo/bar", tr.MainModule())
+
+	// A previous ModulePrefix isn't kept once it's cleared.
+	tr.ModulePrefix = ""
+	assert.NoError(t, tr.Resolve("name"))
+	assert.Equal(t, "github.com/adapap/depth", tr.MainModule())
+}
+
+func TestTree_ResolveMaxPackages(t *testing.T) {
+	imports := map[string][]string{
+		"root": {"a", "b"},
+		"a":    {"c"},
+		"b":    {"d"},
+	}
+	tr := Tree{
+		MaxPackages: 2,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			pkg := &build.Package{ImportPath: name}
+			if im&build.FindOnly == 0 {
+				pkg.Imports = imports[name]
+			}
+			return pkg, nil
+		}},
+	}
+	assert.ErrorIs(t, tr.Resolve("root"), ErrMaxPackages)
+
+	// The root and a are resolved, leaving b as the truncated frontier.
+	if assert.Len(t, tr.Root.Deps, 2) {
+		a, b := tr.Root.Deps[0], tr.Root.Deps[1]
+		assert.False(t, a.Truncated)
+		assert.Len(t, a.Deps, 1)
+		assert.True(t, b.Truncated)
+		assert.Len(t, b.Deps, 0)
+	}
+
+	// The count is reset on each Resolve.
+	tr.MaxPackages = 5
+	assert.NoError(t, tr.Resolve("root"))
+}
+
+func TestTree_ResolveMaxBreadth(t *testing.T) {
+	imports := map[string][]string{
+		"root": {"b", "a", "d", "c"},
+		"a":    {"r", "p", "q"},
+		"b":    {"r"},
+		"r":    {"s"},
+	}
+	var mu sync.Mutex
+	var imported []string
+	tr := Tree{
+		MaxBreadth: 2,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			mu.Lock()
+			imported = append(imported, name)
+			mu.Unlock()
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// The dependencies left out are never imported.
+	assert.ElementsMatch(t, []string{"root", "a", "b", "p", "q", "r", "s"}, imported)
+
+	var names []string
+	for _, dep := range tr.Root.Deps {
+		names = append(names, dep.Name)
+	}
+	assert.Equal(t, []string{"a", "b", "... (2 more)"}, names)
+	assert.Equal(t, 2, tr.Root.Deps[2].Omitted)
+
+	// The only occurrence of r left, beneath b, is the one expanded, so s is still found.
+	if a := tr.Root.Find("a"); assert.NotNil(t, a) {
+		assert.Equal(t, "p", a.Deps[0].Name)
+		assert.Equal(t, "q", a.Deps[1].Name)
+		assert.Equal(t, 1, a.Deps[2].Omitted)
+	}
+	assert.NotNil(t, tr.Root.Find("s"))
+	assert.Equal(t, 6, tr.Stats().Total)
+}
+
+func TestTree_ResolveMaxBreadthStdlibFirst(t *testing.T) {
+	tr := Tree{
+		MaxBreadth: 1,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports := map[string][]string{"root": {"github.com/foo/bar", "strings"}}
+			return &build.Package{ImportPath: name, Imports: imports[name], Goroot: !strings.Contains(name, ".")}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// The default SortMode keeps standard library packages, recognized by their import paths.
+	if assert.Len(t, tr.Root.Deps, 2) {
+		assert.Equal(t, "strings", tr.Root.Deps[0].Name)
+		assert.Equal(t, 1, tr.Root.Deps[1].Omitted)
+	}
+}
+
+func TestTree_ResolveRelative(t *testing.T) {
+	var tr Tree
+	assert.NoError(t, tr.Resolve("./set"))
+	assert.Equal(t, "github.com/adapap/depth/set", tr.Root.Name)
+
+	assert.NoError(t, tr.Resolve("."))
+	assert.Equal(t, "github.com/adapap/depth", tr.Root.Name)
+
+	// Without a package to find, the relative path is kept.
+	assert.Equal(t, ErrRootPkgNotResolved, tr.Resolve("./notreal"))
+	assert.Equal(t, "./notreal", tr.Root.Name)
+	assert.Error(t, tr.Root.Err)
+}
+
+func TestTree_ResolveRelativeImporter(t *testing.T) {
+	// The canonical import path is found by the Importer of the Tree, which places it in
+	// another directory, so the relative path is kept.
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		if name == "github.com/adapap/depth/set" {
+			return &build.Package{ImportPath: name, Dir: "/elsewhere/set"}, nil
+		}
+		return build.Import(name, srcDir, im)
+	}}}
+	assert.NoError(t, tr.Resolve("./set"))
+	assert.Equal(t, "./set", tr.Root.Name)
+}
+
+func TestTree_ResolveBuildTags(t *testing.T) {
+	depNames := func(tr *Tree) []string {
+		var names []string
+		for _, d := range tr.Root.Deps {
+			names = append(names, d.Name)
+		}
+		return names
+	}
+
+	var tr Tree
+	assert.NoError(t, tr.Resolve("./testdata/buildtags"))
+	assert.Equal(t, []string{"strings"}, depNames(&tr))
+	assert.Equal(t, []string{"sometag"}, tr.Root.BuildTags)
+
+	tr = Tree{BuildTags: []string{"sometag"}}
+	assert.NoError(t, tr.Resolve("./testdata/buildtags"))
+	assert.Equal(t, []string{"net/url", "strings"}, depNames(&tr))
+}
+
+func TestTree_ResolveIsCommand(t *testing.T) {
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		if name == "cmd" {
+			return &build.Package{Name: "main", ImportPath: name, Imports: []string{"lib"}}, nil
+		}
+		return &build.Package{Name: name, ImportPath: name}, nil
+	}}}
+	assert.NoError(t, tr.Resolve("cmd"))
+	assert.True(t, tr.Root.IsCommand)
+	if assert.Len(t, tr.Root.Deps, 1) {
+		assert.False(t, tr.Root.Deps[0].IsCommand)
+	}
+
+	b, err := json.Marshal(tr.Root)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"isCommand":true`)
+	assert.Equal(t, 1, strings.Count(string(b), "isCommand"))
+}
+
+func TestTree_ResolveFindOnly(t *testing.T) {
+	var mu sync.Mutex
+	modes := make(map[string]build.ImportMode)
+	tr := Tree{
+		FindOnly: true,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			modes[name] = im
+
+			imports := map[string][]string{"root": {"a", "missing"}, "a": {"c"}}
+			if name == "missing" {
+				return nil, errors.New("not found")
+			}
+			if im == build.FindOnly {
+				return &build.Package{ImportPath: name}, nil
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// Only the root is read, so the dependencies of its direct dependencies are never found,
+	// but those missing are still reported.
+	assert.Equal(t, map[string]build.ImportMode{"root": 0, "a": build.FindOnly, "missing": build.FindOnly}, modes)
+	if assert.Len(t, tr.Root.Deps, 2) {
+		assert.True(t, tr.Root.Deps[0].Resolved)
+		assert.Empty(t, tr.Root.Deps[0].Deps)
+		assert.False(t, tr.Root.Deps[1].Resolved)
+	}
+	assert.Equal(t, 1, tr.Stats().MaxDepth)
+}
+
+func TestTree_ResolveCgo(t *testing.T) {
+	depNames := func(tr *Tree) []string {
+		var names []string
+		for _, d := range tr.Root.Deps {
+			names = append(names, d.Name)
+		}
+		return names
+	}
+
+	enabled, disabled := true, false
+	tr := Tree{CgoEnabled: &enabled}
+	assert.NoError(t, tr.Resolve("./testdata/cgo"))
+	assert.True(t, tr.Root.UsesCgo)
+	assert.Equal(t, []string{"strings", "unsafe"}, depNames(&tr))
+
+	// The file using cgo is excluded when cgo is disabled, along with its imports.
+	tr = Tree{CgoEnabled: &disabled}
+	assert.NoError(t, tr.Resolve("./testdata/cgo"))
+	assert.False(t, tr.Root.UsesCgo)
+	assert.Equal(t, []string{"strings"}, depNames(&tr))
+}
+
+func TestPkg_UsesCgoImport(t *testing.T) {
+	// A package importing C is reported as using cgo, even without CgoFiles.
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return &build.Package{ImportPath: name, Imports: []string{"C", "strings"}}, nil
+	}}}
+	assert.NoError(t, tr.Resolve("root"))
+	assert.True(t, tr.Root.UsesCgo)
+	if assert.Len(t, tr.Root.Deps, 1) {
+		assert.Equal(t, "strings", tr.Root.Deps[0].Name)
+		assert.True(t, tr.Root.Deps[0].UsesCgo)
+	}
+}
+
+func TestTree_ResolveMultiplePackages(t *testing.T) {
+	// The file of the other package is ignored, since the directory is named like multipkg.
+	var tr Tree
+	assert.NoError(t, tr.Resolve("./testdata/multipkg"))
+	if assert.Len(t, tr.Root.Deps, 1) {
+		assert.Equal(t, "strings", tr.Root.Deps[0].Name)
+	}
+
+	tr = Tree{}
+	assert.ErrorIs(t, tr.Resolve("./testdata/multipkg/mismatch"), ErrRootPkgNotResolved)
+	var multiErr *build.MultiplePackageError
+	assert.ErrorAs(t, tr.Root.Err, &multiErr)
+	assert.ErrorContains(t, tr.Root.Err, "directory contains multiple packages: ")
+}
+
+func TestExpectedPkgName(t *testing.T) {
+	assert.Equal(t, "depth", expectedPkgName("github.com/adapap/depth"))
+	assert.Equal(t, "depth", expectedPkgName("github.com/adapap/depth/v2"))
+	assert.Equal(t, "multipkg", expectedPkgName("./testdata/multipkg"))
+	assert.Equal(t, "v2", expectedPkgName("v2"))
+}
+
+func TestTree_ResolveSeparateTestRoots(t *testing.T) {
+	tr := Tree{
+		ResolveXTest:      true,
+		SeparateTestRoots: true,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			if name == "root" {
+				return &build.Package{ImportPath: name, Imports: []string{"a"}, XTestImports: []string{"a", "b", "root"}}, nil
+			}
+			return &build.Package{ImportPath: name, XTestImports: []string{"c"}}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	var visited []string
+	tr.Root.Walk(func(p *Pkg, depth int) bool {
+		visited = append(visited, fmt.Sprintf("%d:%s:%v", depth, p.Name, p.XTest))
+		return true
+	})
+	// Only the Root has a separate test root, the external tests of others are still merged.
+	assert.Equal(t, []string{
+		"0:root:false",
+		"1:a:false", "2:c:true",
+		"1:root [test]:true", "2:a:true", "2:b:true", "3:c:true", "2:root:true",
+	}, visited)
+}
+
+func TestTree_ResolveStopAtExternal(t *testing.T) {
+	tr := Tree{
+		StopAtExternal: true,
+		ModulePrefix:   "github.com/org/app",
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			if im == build.FindOnly {
+				return &build.Package{ImportPath: name}, nil
+			}
+			imports := map[string][]string{
+				"github.com/org/app":     {"github.com/org/app/lib", "github.com/ext/a"},
+				"github.com/org/app/lib": {"github.com/ext/b", "strings"},
+				"github.com/ext/a":       {"github.com/ext/c"},
+				"strings":                {"unicode"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("github.com/org/app"))
+
+	var visited []string
+	tr.Root.Walk(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		return true
+	})
+	// External packages are found, but only first-party and stdlib packages are expanded.
+	assert.Equal(t, []string{
+		"github.com/org/app",
+		"github.com/ext/a",
+		"github.com/org/app/lib", "github.com/ext/b", "strings", "unicode",
+	}, visited)
+}
+
+func TestTree_ResolveOpaquePackages(t *testing.T) {
+	var modes []string
+	var mu sync.Mutex
+	tr := Tree{
+		OpaquePackages: []string{"golang.org/x/net"},
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if im == build.FindOnly {
+				modes = append(modes, name)
+			}
+			imports := map[string][]string{
+				"root":                  {"golang.org/x/net/html", "github.com/foo/bar"},
+				"golang.org/x/net/html": {"golang.org/x/net/html/atom"},
+				"github.com/foo/bar":    {"strings"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// The opaque package is only found, and its dependencies aren't resolved.
+	assert.Equal(t, []string{"golang.org/x/net/html"}, modes)
+	assert.Len(t, tr.Root.Deps, 2)
+	assert.Equal(t, "github.com/foo/bar", tr.Root.Deps[0].Name)
+	assert.Len(t, tr.Root.Deps[0].Deps, 1)
+	assert.Empty(t, tr.Root.Deps[1].Deps)
+}
+
+func TestTree_ResolveLogger(t *testing.T) {
+	var buf bytes.Buffer
+	tr := Tree{
+		MaxDepth: 2,
+		Logger:   slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			if name == "missing" {
+				return nil, fmt.Errorf("cannot find package %q", name)
+			}
+			imports := map[string][]string{
+				"root": {"a", "b", "missing"},
+				"a":    {"b", "c"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	out := buf.String()
+	assert.Contains(t, out, "msg=imported pkg=root")
+	assert.Contains(t, out, `msg="skipping dependencies" pkg=b reason=duplicate`)
+	assert.Contains(t, out, `msg="skipping dependencies" pkg=c reason="max depth"`)
+	assert.Contains(t, out, `msg="import failed" pkg=missing`)
+}
+
+func TestTree_ResolveConcurrent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("resolves net/http several times")
+	}
+
+	// Clones share the CachingImporter, while each is resolved concurrently.
+	base := Tree{ResolveInternal: true, Importer: NewCachingImporter()}
+	trees := make([]*Tree, 4)
+	var wg sync.WaitGroup
+	for i := range trees {
+		trees[i] = base.Clone()
+		wg.Add(1)
+		go func(tr *Tree) {
+			defer wg.Done()
+			assert.NoError(t, tr.Resolve("net/http"))
+		}(trees[i])
+	}
+	wg.Wait()
+
+	for _, tr := range trees[1:] {
+		assert.Equal(t, trees[0].Stats(), tr.Stats())
+	}
+}
+
+func TestTree_ResolveDir(t *testing.T) {
+	var tr Tree
+	assert.NoError(t, tr.ResolveDir("./testdata/tree"))
+
+	// Hidden and vendor directories are skipped, and a, imported by c, is only expanded once.
+	assert.Equal(t, "./testdata/tree/...", tr.Root.Name)
+	assert.Len(t, tr.Root.Deps, 3)
+	names := []string{tr.Root.Deps[0].Name, tr.Root.Deps[1].Name, tr.Root.Deps[2].Name}
+	assert.ElementsMatch(t, []string{
+		"github.com/adapap/depth/testdata/tree/a",
+		"github.com/adapap/depth/testdata/tree/b",
+		"github.com/adapap/depth/testdata/tree/b/c",
+	}, names)
+	assert.Equal(t, 5, tr.Root.Stats().Total)
+
+	c := tr.Root.Find("github.com/adapap/depth/testdata/tree/b/c")
+	assert.Len(t, c.Deps, 1)
+	assert.Empty(t, c.Deps[0].Deps)
+
+	err := tr.ResolveDir("./testdata/tree/.hidden/missing")
+	assert.Error(t, err)
+}
+
+func TestTree_ResolveProgress(t *testing.T) {
+	var counts []int
+	tr := Tree{
+		Progress: func(resolved int) {
+			counts = append(counts, resolved)
+		},
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports := map[string][]string{"root": {"a", "b"}, "a": {"b"}}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+
+	// root, a, b and the b beneath a, reported in order.
+	assert.Equal(t, []int{1, 2, 3, 4}, counts)
+
+	// Progress is reported alongside the packages streamed.
+	counts = nil
+	pkgs, errs := tr.ResolveStream(context.Background(), "root")
+	var streamed int
+	for range pkgs {
+		streamed++
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, 4, streamed)
+	assert.Equal(t, []int{1, 2, 3, 4}, counts)
+}
@@ -0,0 +1,85 @@
+package depth
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DOTOptions customize the Graphviz DOT output of a Formatter returned by NewDOTFormatter.
+type DOTOptions struct {
+	// NodeAttrs, if set, is called for each unique package to return attributes of its node,
+	// such as "color" or "label", which are merged over the defaults.
+	NodeAttrs func(p *Pkg) map[string]string
+}
+
+// NewDOTFormatter returns a Formatter writing the dependencies as a Graphviz DOT digraph, in
+// which each unique package is a node and each unique import an edge. By default, internal
+// packages are filled grey and external packages blue.
+func NewDOTFormatter(opts DOTOptions) Formatter {
+	return FormatterFunc(func(w io.Writer, root *Pkg) error {
+		return writeDOT(w, root, opts)
+	})
+}
+
+func writeDOT(w io.Writer, root *Pkg, opts DOTOptions) error {
+	// Nodes take their attributes from the first occurrence of each package, preferring the
+	// occurrence whose dependencies were resolved.
+	nodes := map[string]*Pkg{root.Name: root}
+	var edges [][2]string
+	for from, to := range root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		if prev, ok := nodes[to.Name]; !ok || (!prev.expanded && to.expanded) {
+			nodes[to.Name] = to
+		}
+		edges = append(edges, [2]string{from.Name, to.Name})
+	}
+	slices.SortFunc(edges, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+
+	var b strings.Builder
+	b.WriteString("digraph deps {\n")
+	for _, name := range slices.Sorted(maps.Keys(nodes)) {
+		attrs := dotNodeAttrs(nodes[name])
+		if opts.NodeAttrs != nil {
+			maps.Copy(attrs, opts.NodeAttrs(nodes[name]))
+		}
+		fmt.Fprintf(&b, "  %s [", dotQuote(name))
+		for idx, key := range slices.Sorted(maps.Keys(attrs)) {
+			if idx > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%s=%s", key, dotQuote(attrs[key]))
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e[0]), dotQuote(e[1]))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotNodeAttrs returns the default attributes of the node of a Pkg.
+func dotNodeAttrs(p *Pkg) map[string]string {
+	fill := "lightblue"
+	if p.Internal {
+		fill = "lightgrey"
+	}
+	return map[string]string{"style": "filled", "fillcolor": fill}
+}
+
+// dotQuote returns the string provided as a quoted DOT ID.
+func dotQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
@@ -0,0 +1,60 @@
+package depth
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDOTFormatter(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true},
+		{Name: `github.com/foo/"bar"`, Deps: []Pkg{
+			{Name: "strings", Internal: true},
+		}},
+		{Name: "... (2 more)", Omitted: 2},
+	}}
+
+	var b strings.Builder
+	assert.NoError(t, NewDOTFormatter(DOTOptions{}).Format(&b, &p))
+	assert.Equal(t, `digraph deps {
+  "github.com/foo/\"bar\"" [fillcolor="lightblue", style="filled"];
+  "root" [fillcolor="lightblue", style="filled"];
+  "strings" [fillcolor="lightgrey", style="filled"];
+  "github.com/foo/\"bar\"" -> "strings";
+  "root" -> "github.com/foo/\"bar\"";
+  "root" -> "strings";
+}
+`, b.String())
+}
+
+func ExampleNewDOTFormatter() {
+	p := Pkg{Name: "github.com/foo/cmd", Deps: []Pkg{
+		{Name: "fmt", Internal: true},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "github.com/evil/lib"},
+		}},
+	}}
+
+	// Banned packages are colored red, overriding the default fill color of their nodes.
+	banned := []string{"github.com/evil"}
+	f := NewDOTFormatter(DOTOptions{NodeAttrs: func(p *Pkg) map[string]string {
+		if MatchesPatterns(p.Name, banned, nil) {
+			return map[string]string{"fillcolor": "red"}
+		}
+		return nil
+	}})
+	f.Format(os.Stdout, &p)
+	// Output:
+	// digraph deps {
+	//   "fmt" [fillcolor="lightgrey", style="filled"];
+	//   "github.com/evil/lib" [fillcolor="red", style="filled"];
+	//   "github.com/foo/bar" [fillcolor="lightblue", style="filled"];
+	//   "github.com/foo/cmd" [fillcolor="lightblue", style="filled"];
+	//   "github.com/foo/bar" -> "github.com/evil/lib";
+	//   "github.com/foo/cmd" -> "fmt";
+	//   "github.com/foo/cmd" -> "github.com/foo/bar";
+	// }
+}
@@ -0,0 +1,95 @@
+package depth
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// markPotentiallyExported sets PotentiallyExported on the direct, non-test dependencies of
+// the Pkg imported by any of its files declaring exported identifiers. Packages within an
+// internal directory have no exported API, so none of their dependencies are marked.
+func (p *Pkg) markPotentiallyExported() {
+	if p.Raw == nil || p.Raw.Dir == "" || isInternalPath(p.Name) {
+		return
+	}
+
+	exported := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range p.Raw.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(p.Raw.Dir, name), nil, parser.SkipObjectResolution)
+		if err != nil || !declaresExported(f) {
+			continue
+		}
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				exported[path] = true
+			}
+		}
+	}
+
+	for idx := range p.Deps {
+		dep := &p.Deps[idx]
+		dep.PotentiallyExported = !dep.Test && exported[dep.Name]
+	}
+}
+
+// isInternalPath returns true if the import path provided is within an internal directory.
+func isInternalPath(name string) bool {
+	return slices.Contains(strings.Split(name, "/"), "internal")
+}
+
+// declaresExported returns true if the file declares any exported identifiers at the top
+// level, or exported methods on exported types.
+func declaresExported(f *ast.File) bool {
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.IsExported() && (d.Recv == nil || receiverExported(d.Recv)) {
+				return true
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						return true
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.IsExported() {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// receiverExported returns true if the base type of the method receiver is exported.
+func receiverExported(recv *ast.FieldList) bool {
+	if len(recv.List) == 0 {
+		return false
+	}
+	typ := recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		case *ast.Ident:
+			return t.IsExported()
+		default:
+			return false
+		}
+	}
+}
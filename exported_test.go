@@ -0,0 +1,52 @@
+package depth
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_ResolvePotentiallyExported(t *testing.T) {
+	var tr Tree
+	assert.NoError(t, tr.Resolve("./testdata/exported"))
+
+	// Only strings is imported by a file declaring exported identifiers.
+	assert.Len(t, tr.Root.Deps, 2)
+	assert.Equal(t, "bytes", tr.Root.Deps[0].Name)
+	assert.False(t, tr.Root.Deps[0].PotentiallyExported)
+	assert.Equal(t, "strings", tr.Root.Deps[1].Name)
+	assert.True(t, tr.Root.Deps[1].PotentiallyExported)
+
+	// Transitive dependencies aren't marked.
+	for _, d := range tr.Root.Deps[1].Deps {
+		assert.False(t, d.PotentiallyExported, d.Name)
+	}
+}
+
+func Test_declaresExported(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"package a\nfunc Foo() {}", true},
+		{"package a\nfunc foo() {}", false},
+		{"package a\ntype T struct{}", true},
+		{"package a\nvar x, Y int", true},
+		{"package a\nconst x = 1", false},
+		{"package a\ntype t struct{}\nfunc (*t) Foo() {}", false},
+		{"package a\ntype T[K any] struct{}\nfunc (T[K]) Foo() {}", true},
+	}
+	for _, tc := range tests {
+		f, err := parser.ParseFile(token.NewFileSet(), "a.go", tc.src, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, declaresExported(f), tc.src)
+	}
+}
+
+func Test_isInternalPath(t *testing.T) {
+	assert.True(t, isInternalPath("github.com/org/repo/internal/foo"))
+	assert.True(t, isInternalPath("internal/abi"))
+	assert.False(t, isInternalPath("github.com/org/internals"))
+}
@@ -0,0 +1,31 @@
+package depth
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Formatter writes a resolved Pkg and its dependencies to a Writer in a particular format,
+// allowing custom output formats to be implemented.
+type Formatter interface {
+	Format(w io.Writer, root *Pkg) error
+}
+
+// FormatterFunc is an adapter allowing an ordinary function to be used as a Formatter.
+type FormatterFunc func(w io.Writer, root *Pkg) error
+
+// Format calls f(w, root).
+func (f FormatterFunc) Format(w io.Writer, root *Pkg) error {
+	return f(w, root)
+}
+
+// NewJSONFormatter returns a Formatter writing the Pkg and its nested dependencies as
+// indented JSON.
+func NewJSONFormatter() Formatter {
+	return FormatterFunc(func(w io.Writer, root *Pkg) error {
+		// Placeholders of omitted dependencies would appear to be packages.
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(root.WithoutOmitted())
+	})
+}
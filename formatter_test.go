@@ -0,0 +1,28 @@
+package depth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJSONFormatter(t *testing.T) {
+	p := Pkg{Name: "root", Resolved: true, Deps: []Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "... (2 more)", Omitted: 2},
+	}}
+
+	var b strings.Builder
+	assert.NoError(t, NewJSONFormatter().Format(&b, &p))
+	assert.True(t, strings.HasPrefix(b.String(), "{\n  \"name\": \"root\""))
+
+	// Placeholders of omitted dependencies are left out.
+	var decoded Pkg
+	assert.NoError(t, json.Unmarshal([]byte(b.String()), &decoded))
+	assert.Equal(t, "root", decoded.Name)
+	if assert.Len(t, decoded.Deps, 1) {
+		assert.Equal(t, "strings", decoded.Deps[0].Name)
+	}
+}
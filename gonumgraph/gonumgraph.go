@@ -0,0 +1,50 @@
+// Package gonumgraph converts resolved dependency trees into gonum graphs, so the algorithms
+// of gonum.org/v1/gonum/graph, such as centrality, shortest paths and community detection,
+// can be run over them. It's a separate package so that only those using it depend on gonum.
+package gonumgraph
+
+import (
+	"slices"
+
+	"github.com/adapap/depth"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// FromTree returns a directed graph with a node for each unique package in the resolved
+// Tree, including its Root, and an edge from each package to each package it imports, along
+// with the names of the packages by the IDs of their nodes. IDs are assigned in order of the
+// sorted names, so they're stable between runs. If the Tree has not been resolved, an empty
+// graph is returned.
+func FromTree(t *depth.Tree) (*simple.DirectedGraph, map[int64]string) {
+	g := simple.NewDirectedGraph()
+	names := make(map[int64]string)
+	if t.Root == nil {
+		return g, names
+	}
+
+	var sorted []string
+	t.Root.WalkPackages(func(p *depth.Pkg, _ int) bool {
+		if p.Omitted == 0 {
+			sorted = append(sorted, p.Name)
+		}
+		return true
+	})
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	ids := make(map[string]int64, len(sorted))
+	for idx, name := range sorted {
+		id := int64(idx)
+		ids[name], names[id] = id, name
+		g.AddNode(simple.Node(id))
+	}
+
+	for from, to := range t.Root.Edges {
+		// Omitted dependencies are unknown, and simple graphs can't hold self edges.
+		if to.Omitted > 0 || from.Name == to.Name {
+			continue
+		}
+		g.SetEdge(g.NewEdge(simple.Node(ids[from.Name]), simple.Node(ids[to.Name])))
+	}
+	return g, names
+}
@@ -0,0 +1,35 @@
+package gonumgraph
+
+import (
+	"testing"
+
+	"github.com/adapap/depth"
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+func TestFromTree(t *testing.T) {
+	var tr depth.Tree
+	tr.Root = &depth.Pkg{Name: "root", Deps: []depth.Pkg{
+		{Name: "b", Deps: []depth.Pkg{{Name: "c"}}},
+		{Name: "a", Deps: []depth.Pkg{{Name: "b"}, {Name: "... 2 more", Omitted: 2}}},
+	}}
+
+	g, names := FromTree(&tr)
+	assert.Equal(t, map[int64]string{0: "a", 1: "b", 2: "c", 3: "root"}, names)
+	assert.Equal(t, 4, g.Nodes().Len())
+	assert.Equal(t, 4, g.Edges().Len())
+	assert.True(t, g.HasEdgeFromTo(3, 0))
+	assert.True(t, g.HasEdgeFromTo(0, 1))
+	assert.False(t, g.HasEdgeFromTo(1, 0))
+
+	sorted, err := topo.Sort(g)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), sorted[0].ID())
+}
+
+func TestFromTree_Unresolved(t *testing.T) {
+	g, names := FromTree(&depth.Tree{})
+	assert.Equal(t, 0, g.Nodes().Len())
+	assert.Empty(t, names)
+}
@@ -0,0 +1,47 @@
+package depth
+
+import (
+	"bufio"
+	"fmt"
+	"go/version"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// checkGOROOT returns an error if the directory provided doesn't contain the source of a
+// standard library.
+func checkGOROOT(goroot string) error {
+	if info, err := os.Stat(filepath.Join(goroot, "src")); err != nil || !info.IsDir() {
+		return fmt.Errorf("invalid GOROOT %q: no src directory found", goroot)
+	}
+	return nil
+}
+
+// releaseTags returns the release tags satisfied by the toolchain installed at the GOROOT
+// provided, such as go1.1 through go1.20 for Go 1.20, as read from its VERSION file. False
+// is returned if the version can't be determined, as for toolchains built from source.
+func releaseTags(goroot string) ([]string, bool) {
+	f, err := os.Open(filepath.Join(goroot, "VERSION"))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		return nil, false
+	}
+	lang := version.Lang(strings.TrimSpace(s.Text()))
+	minor, err := strconv.Atoi(strings.TrimPrefix(lang, "go1."))
+	if lang == "" || err != nil {
+		return nil, false
+	}
+
+	tags := make([]string, 0, minor)
+	for i := 1; i <= minor; i++ {
+		tags = append(tags, "go1."+strconv.Itoa(i))
+	}
+	return tags, true
+}
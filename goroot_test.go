@@ -0,0 +1,38 @@
+package depth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_ResolveGOROOT(t *testing.T) {
+	goroot, err := filepath.Abs("testdata/goroot")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tr := Tree{GOROOT: goroot}
+	assert.NoError(t, tr.Resolve("strings"))
+
+	// The fake standard library's strings only imports errors.
+	assert.Len(t, tr.Root.Deps, 1)
+	assert.Equal(t, "errors", tr.Root.Deps[0].Name)
+	assert.True(t, tr.Root.Deps[0].Internal)
+	assert.Equal(t, filepath.Join(goroot, "src", "strings"), tr.Root.Raw.Dir)
+
+	tr = Tree{GOROOT: filepath.Join(goroot, "missing")}
+	assert.ErrorContains(t, tr.Resolve("strings"), "invalid GOROOT")
+}
+
+func Test_releaseTags(t *testing.T) {
+	tags, ok := releaseTags("testdata/goroot")
+	assert.True(t, ok)
+	assert.Len(t, tags, 20)
+	assert.Equal(t, "go1.1", tags[0])
+	assert.Equal(t, "go1.20", tags[19])
+
+	_, ok = releaseTags("testdata/missing")
+	assert.False(t, ok)
+}
@@ -0,0 +1,419 @@
+package depth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/adapap/depth/set"
+)
+
+// ErrImportCycle is returned, wrapped with the packages forming the cycle, when the tree
+// cannot be sorted topologically.
+var ErrImportCycle = errors.New("import cycle")
+
+// graph is the adjacency list of a resolved tree, mapping each package name to the
+// names of the packages it imports.
+type graph map[string][]string
+
+// graph returns the adjacency list of the Pkg and its dependencies. Since only the first
+// occurrence of each package has its dependencies resolved, the imports of every occurrence
+// are merged. Placeholders of omitted dependencies are left out, as they aren't packages.
+func (p *Pkg) graph() graph {
+	g := graph{p.Name: nil}
+	for from, to := range p.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		g[from.Name] = append(g[from.Name], to.Name)
+		if _, ok := g[to.Name]; !ok {
+			g[to.Name] = nil
+		}
+	}
+	return g
+}
+
+// reachable returns the set of package names reachable from the name provided, without
+// passing through the excluded name.
+func (g graph) reachable(from, exclude string) set.Set[string] {
+	seen := set.New[string]()
+	if from == exclude {
+		return seen
+	}
+
+	stack := []string{from}
+	seen.Add(from)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range g[name] {
+			if dep == exclude || seen.Has(dep) {
+				continue
+			}
+			seen.Add(dep)
+			stack = append(stack, dep)
+		}
+	}
+	return seen
+}
+
+// ExclusiveDeps returns the sorted names of the packages that are only reachable from the
+// Root through the named package, and would therefore be removed from the tree along with it.
+// The named package itself is not included.
+func (t *Tree) ExclusiveDeps(name string) []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	g := t.Root.graph()
+	with := g.reachable(t.Root.Name, "")
+	without := g.reachable(t.Root.Name, name)
+
+	var exclusive []string
+	for dep := range g {
+		if dep != name && with.Has(dep) && !without.Has(dep) {
+			exclusive = append(exclusive, dep)
+		}
+	}
+	sort.Strings(exclusive)
+	return exclusive
+}
+
+// TestOnlyDeps returns the sorted names of the packages the Root only depends on because of
+// tests, since no chain of non-test imports from the Root reaches them. Unlike the Test flag,
+// which only describes a single import, this accounts for packages also imported by non-test
+// files elsewhere in the tree, so the packages returned are the true cost of the tests.
+func (t *Tree) TestOnlyDeps() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	all := graph{}
+	production := graph{t.Root.Name: nil}
+	for from, to := range t.Root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		all[to.Name] = nil
+		if !to.Test {
+			production[from.Name] = append(production[from.Name], to.Name)
+		}
+	}
+	reached := production.reachable(t.Root.Name, "")
+
+	var names []string
+	for name := range all {
+		if name != t.Root.Name && !reached.Has(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExplainPaths returns each chain of imports from the Root to the target package, as the
+// names of the packages along it. An empty slice is returned if the target isn't found.
+func (t *Tree) ExplainPaths(target string) [][]string {
+	if t.Root == nil {
+		return [][]string{}
+	}
+
+	return t.Root.ExplainPaths(target)
+}
+
+// ExplainPaths returns each chain of imports from the Pkg to the target package, as the
+// names of the packages along it. An empty slice is returned if the target isn't found.
+func (p *Pkg) ExplainPaths(target string) [][]string {
+	paths := [][]string{}
+	p.explainPaths(target, nil, &paths)
+	return paths
+}
+
+func (p *Pkg) explainPaths(target string, stack []string, paths *[][]string) {
+	stack = append(stack, p.Name)
+	if p.Name == target {
+		*paths = append(*paths, slices.Clone(stack))
+	}
+	for i := range p.Deps {
+		p.Deps[i].explainPaths(target, stack, paths)
+	}
+}
+
+// defaultMaxPaths is the number of paths returned by PathsBetween for a Tree without MaxPaths.
+const defaultMaxPaths = 100
+
+// PathsBetween returns the chains of imports from one package to another anywhere in the
+// tree, as the names of the packages along each, without visiting any package twice. The
+// shortest paths are returned first, and at most MaxPaths are returned, since dense graphs
+// can have a combinatorial number of them. An empty slice is returned if none are found.
+func (t *Tree) PathsBetween(from, to string) [][]string {
+	paths := [][]string{}
+	if t.Root == nil {
+		return paths
+	}
+
+	g := t.Root.graph()
+	if _, ok := g[from]; !ok {
+		return paths
+	}
+	for _, deps := range g {
+		sort.Strings(deps)
+	}
+
+	// Only packages from which the target can be reached are worth visiting.
+	reaches := graph{}
+	for name, deps := range g {
+		for _, dep := range deps {
+			reaches[dep] = append(reaches[dep], name)
+		}
+	}
+	useful := reaches.reachable(to, "")
+
+	limit := t.MaxPaths
+	if limit <= 0 {
+		limit = defaultMaxPaths
+	}
+
+	// Paths are extended breadth first, so they are found in order of length.
+	queue := [][]string{{from}}
+	for len(queue) > 0 && len(paths) < limit {
+		path := queue[0]
+		queue = queue[1:]
+
+		last := path[len(path)-1]
+		if last == to {
+			paths = append(paths, path)
+			continue
+		}
+		for _, dep := range g[last] {
+			if useful.Has(dep) && !slices.Contains(path, dep) {
+				queue = append(queue, append(slices.Clone(path), dep))
+			}
+		}
+	}
+	return paths
+}
+
+// ComputeFanIn annotates every Pkg in the tree with the number of distinct packages that
+// import it, across the entire tree. Placeholders of omitted dependencies are left at zero.
+func (t *Tree) ComputeFanIn() {
+	if t.Root == nil {
+		return
+	}
+
+	importers := make(map[string]set.Set[string])
+	for from, to := range t.Root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		if importers[to.Name] == nil {
+			importers[to.Name] = set.New[string]()
+		}
+		importers[to.Name].Add(from.Name)
+	}
+
+	t.Root.Walk(func(p *Pkg, depth int) bool {
+		p.ImportedBy = 0
+		if s, ok := importers[p.Name]; ok {
+			p.ImportedBy = s.Len()
+		}
+		return true
+	})
+}
+
+// TopoSort returns the names of the Root and every package it depends on in topological
+// order, listing each package after all of its dependencies. Ties are broken by name, so the
+// order is deterministic.
+//
+// Import cycles, which can only be introduced by test dependencies, prevent a topological
+// order, in which case an error wrapping ErrImportCycle and naming the cycle is returned.
+func (t *Tree) TopoSort() ([]string, error) {
+	if t.Root == nil {
+		return nil, nil
+	}
+
+	g := t.Root.graph()
+	names := make([]string, 0, len(g))
+	for name, deps := range g {
+		names = append(names, name)
+		sort.Strings(deps)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g))
+	order := make([]string, 0, len(g))
+	var stack []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(stack[slices.Index(stack, name):]), name)
+			return fmt.Errorf("%w: %s", ErrImportCycle, strings.Join(cycle, " -> "))
+		}
+
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ImportCycles returns the import cycles among the packages in the tree, each as the names
+// of the packages along it, beginning and ending with the same package. If firstParty is
+// true, only the packages of the main module, identified by the ModulePrefix, are considered,
+// since cycles through other packages are usually introduced by test dependencies outside of
+// the project's control.
+//
+// A cycle is returned for each import that closes one during a depth-first traversal of the
+// packages in order of name, so every package within a cycle is reported at least once,
+// though not every cycle through it is.
+func (t *Tree) ImportCycles(firstParty bool) [][]string {
+	cycles := [][]string{}
+	if t.Root == nil {
+		return cycles
+	}
+
+	g := t.Root.graph()
+	if firstParty {
+		for name, deps := range g {
+			if !t.inMainModule(name) {
+				delete(g, name)
+				continue
+			}
+			g[name] = slices.DeleteFunc(deps, func(dep string) bool {
+				return !t.inMainModule(dep)
+			})
+		}
+	}
+
+	names := make([]string, 0, len(g))
+	for name, deps := range g {
+		names = append(names, name)
+		sort.Strings(deps)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g))
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g[name] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				cycles = append(cycles, append(slices.Clone(stack[slices.Index(stack, dep):]), dep))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+// GraphMetrics describes the shape of the deduplicated graph of a resolved tree, in which
+// each unique package is a node and each unique import between two packages is an edge.
+type GraphMetrics struct {
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+	// AvgOutDegree is the average number of packages imported by each package.
+	AvgOutDegree float64 `json:"avgOutDegree"`
+	// Density is the ratio of Edges to the number of edges possible between the Nodes,
+	// from 0 for packages without any imports to 1 when every package imports every other.
+	Density float64 `json:"density"`
+}
+
+// GraphMetrics computes the GraphMetrics of the Root and its dependencies. Packages omitted
+// due to the MaxBreadth are not counted. If the Tree has not been resolved, a zero
+// GraphMetrics is returned.
+func (t *Tree) GraphMetrics() GraphMetrics {
+	if t.Root == nil {
+		return GraphMetrics{}
+	}
+
+	nodes := map[string]struct{}{t.Root.Name: {}}
+	var m GraphMetrics
+	for _, to := range t.Root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		nodes[to.Name] = struct{}{}
+		m.Edges++
+	}
+
+	m.Nodes = len(nodes)
+	m.AvgOutDegree = float64(m.Edges) / float64(m.Nodes)
+	if m.Nodes > 1 {
+		m.Density = float64(m.Edges) / float64(m.Nodes*(m.Nodes-1))
+	}
+	return m
+}
+
+// EdgeStats counts the unique imports between two packages in a resolved tree, separating
+// those introduced by the test files of the importing package from the rest.
+type EdgeStats struct {
+	Total      int `json:"total"`
+	Test       int `json:"test"`
+	Production int `json:"production"`
+}
+
+// EdgeStats computes the EdgeStats of the Root and its dependencies. An edge is a test edge
+// if the imported Pkg is a Test dependency, so edges merged by MergeTestDeps are production
+// edges. Packages omitted due to the MaxBreadth are not counted.
+func (t *Tree) EdgeStats() EdgeStats {
+	var s EdgeStats
+	if t.Root == nil {
+		return s
+	}
+
+	for _, to := range t.Root.Edges {
+		if to.Omitted > 0 {
+			continue
+		}
+		s.Total++
+		if to.Test {
+			s.Test++
+		} else {
+			s.Production++
+		}
+	}
+	return s
+}
@@ -0,0 +1,228 @@
+package depth
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTree returns a resolved Tree shaped like so, where the second occurrence of c is
+// collapsed:
+//
+//	root
+//	├ a
+//	│ ├ c
+//	│ │ └ e
+//	│ └ d
+//	└ b
+//	  └ c
+func testTree() *Tree {
+	return &Tree{Root: &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Depth: 1, Deps: []Pkg{
+			{Name: "c", Depth: 2, Deps: []Pkg{{Name: "e", Depth: 3}}},
+			{Name: "d", Depth: 2},
+		}},
+		{Name: "b", Depth: 1, Deps: []Pkg{{Name: "c", Depth: 2}}},
+	}}}
+}
+
+// breadthTree returns a Tree resolved with a MaxBreadth of 1, shaped like so:
+//
+//	root
+//	└ a
+//	  ├ c
+//	  └ ... (1 more)
+func breadthTree(t *testing.T) *Tree {
+	tr := &Tree{MaxBreadth: 1, Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		imports := map[string][]string{"root": {"a"}, "a": {"c", "d"}}
+		return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+	}}}
+	assert.NoError(t, tr.Resolve("root"))
+	return tr
+}
+
+func TestTree_ExclusiveDeps(t *testing.T) {
+	tr := testTree()
+
+	assert.Equal(t, []string{"d"}, tr.ExclusiveDeps("a"))
+	assert.Empty(t, tr.ExclusiveDeps("b"))
+	assert.Equal(t, []string{"e"}, tr.ExclusiveDeps("c"))
+	assert.Empty(t, tr.ExclusiveDeps("notreal"))
+
+	var empty Tree
+	assert.Empty(t, empty.ExclusiveDeps("a"))
+
+	// Placeholders of omitted dependencies aren't packages to be removed.
+	assert.Equal(t, []string{"c"}, breadthTree(t).ExclusiveDeps("a"))
+}
+
+func TestTree_ComputeFanIn(t *testing.T) {
+	tr := testTree()
+	tr.ComputeFanIn()
+
+	fanIn := make(map[string]int)
+	tr.Root.Walk(func(p *Pkg, depth int) bool {
+		fanIn[p.Name] = p.ImportedBy
+		return true
+	})
+	assert.Equal(t, map[string]int{"root": 0, "a": 1, "b": 1, "c": 2, "d": 1, "e": 1}, fanIn)
+
+	// Both occurrences of c are annotated.
+	assert.Equal(t, 2, tr.Root.Deps[1].Deps[0].ImportedBy)
+
+	tr = breadthTree(t)
+	tr.ComputeFanIn()
+	assert.Equal(t, 1, tr.Root.Deps[0].Deps[0].ImportedBy)
+	assert.Equal(t, 0, tr.Root.Deps[0].Deps[1].ImportedBy)
+}
+
+func TestTree_ExplainPaths(t *testing.T) {
+	var tr Tree
+	assert.Equal(t, [][]string{}, tr.ExplainPaths("strings"))
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings"},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings"},
+			{Name: "errors"},
+		}},
+	}}
+	assert.Equal(t, [][]string{
+		{"root", "strings"},
+		{"root", "github.com/foo/bar", "strings"},
+	}, tr.ExplainPaths("strings"))
+	assert.Equal(t, [][]string{{"root"}}, tr.ExplainPaths("root"))
+	assert.Equal(t, [][]string{}, tr.ExplainPaths("fmt"))
+}
+
+func TestTree_TestOnlyDeps(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.TestOnlyDeps())
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{
+			{Name: "strings"},
+		}},
+		{Name: "testify", Test: true, Deps: []Pkg{
+			{Name: "yaml"},
+			{Name: "a"},
+		}},
+		{Name: "b", Deps: []Pkg{
+			{Name: "fmt", Test: true},
+		}},
+	}}
+
+	// a is also imported by non-test files, and the non-test imports of test dependencies
+	// are still only needed for tests.
+	assert.Equal(t, []string{"fmt", "testify", "yaml"}, tr.TestOnlyDeps())
+}
+
+func TestTree_PathsBetween(t *testing.T) {
+	var tr Tree
+	assert.Equal(t, [][]string{}, tr.PathsBetween("a", "d"))
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{
+			{Name: "b", Deps: []Pkg{{Name: "d"}}},
+			{Name: "c", Deps: []Pkg{{Name: "b"}, {Name: "d"}}},
+			{Name: "d"},
+		}},
+		{Name: "e", Deps: []Pkg{{Name: "a"}}},
+	}}
+	assert.Equal(t, [][]string{
+		{"a", "d"},
+		{"a", "b", "d"},
+		{"a", "c", "d"},
+		{"a", "c", "b", "d"},
+	}, tr.PathsBetween("a", "d"))
+	assert.Equal(t, [][]string{{"e", "a", "b"}, {"e", "a", "c", "b"}}, tr.PathsBetween("e", "b"))
+	assert.Equal(t, [][]string{}, tr.PathsBetween("d", "a"))
+	assert.Equal(t, [][]string{}, tr.PathsBetween("fmt", "a"))
+
+	// The shortest paths are kept when capped.
+	tr.MaxPaths = 2
+	assert.Equal(t, [][]string{{"a", "d"}, {"a", "b", "d"}}, tr.PathsBetween("a", "d"))
+}
+
+func TestTree_TopoSort(t *testing.T) {
+	var tr Tree
+	order, err := tr.TopoSort()
+	assert.NoError(t, err)
+	assert.Nil(t, order)
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "b", Deps: []Pkg{{Name: "strings"}, {Name: "c"}}},
+		{Name: "a", Deps: []Pkg{{Name: "b"}}},
+		{Name: "strings"},
+	}}
+	order, err = tr.TopoSort()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "strings", "b", "a", "root"}, order)
+
+	// The tests of c import a, which depends on c.
+	tr.Root.Deps[0].Deps[1].Deps = []Pkg{{Name: "a", Test: true}}
+	_, err = tr.TopoSort()
+	assert.ErrorIs(t, err, ErrImportCycle)
+	assert.EqualError(t, err, "import cycle: a -> b -> c -> a")
+
+	// Placeholders of omitted dependencies aren't sorted as packages.
+	order, err = breadthTree(t).TopoSort()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "a", "root"}, order)
+}
+
+func TestTree_ImportCycles(t *testing.T) {
+	var tr Tree
+	assert.Equal(t, [][]string{}, tr.ImportCycles(false))
+
+	tr = Tree{ModulePrefix: "github.com/org/root", Root: &Pkg{Name: "github.com/org/root", Deps: []Pkg{
+		{Name: "github.com/org/root/a", Deps: []Pkg{
+			{Name: "github.com/org/root/b", Deps: []Pkg{
+				{Name: "github.com/org/root/a", Test: true},
+			}},
+		}},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "github.com/foo/baz", Deps: []Pkg{
+				{Name: "github.com/foo/bar", Test: true},
+			}},
+		}},
+		{Name: "strings"},
+	}}}
+
+	assert.Equal(t, [][]string{
+		{"github.com/foo/bar", "github.com/foo/baz", "github.com/foo/bar"},
+		{"github.com/org/root/a", "github.com/org/root/b", "github.com/org/root/a"},
+	}, tr.ImportCycles(false))
+	assert.Equal(t, [][]string{
+		{"github.com/org/root/a", "github.com/org/root/b", "github.com/org/root/a"},
+	}, tr.ImportCycles(true))
+
+	// Without cycles among the first-party packages, none are returned.
+	tr.Root.Deps[0].Deps[0].Deps = nil
+	assert.Equal(t, [][]string{}, tr.ImportCycles(true))
+}
+
+func TestTree_GraphMetrics(t *testing.T) {
+	tr := testTree()
+
+	// The import of c by b is an edge of its own, even though c is collapsed there.
+	assert.Equal(t, GraphMetrics{Nodes: 6, Edges: 6, AvgOutDegree: 1, Density: 0.2}, tr.GraphMetrics())
+
+	var empty Tree
+	assert.Equal(t, GraphMetrics{}, empty.GraphMetrics())
+}
+
+func TestTree_EdgeStats(t *testing.T) {
+	tr := testTree()
+	tr.Root.Deps = append(tr.Root.Deps, Pkg{Name: "f", Test: true, Depth: 1, Deps: []Pkg{
+		{Name: "e", Depth: 2},
+	}})
+	tr.Root.Deps[0].Deps = append(tr.Root.Deps[0].Deps, Pkg{Name: "f", Test: true, Depth: 2})
+
+	// The import of e by f is a production edge, as it isn't imported by the tests of f.
+	assert.Equal(t, EdgeStats{Total: 9, Test: 2, Production: 7}, tr.EdgeStats())
+
+	var empty Tree
+	assert.Equal(t, EdgeStats{}, empty.EdgeStats())
+}
@@ -0,0 +1,71 @@
+package depth
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// NewGraphMLFormatter returns a Formatter writing the dependencies as a GraphML document, with
+// a node for each unique package and an edge for each unique import, for tools such as yEd
+// or Gephi.
+func NewGraphMLFormatter() Formatter {
+	return FormatterFunc(func(w io.Writer, root *Pkg) error {
+		// Placeholders of omitted dependencies would appear to be packages.
+		return writeGraphML(w, root.WithoutOmitted())
+	})
+}
+
+func writeGraphML(w io.Writer, root *Pkg) error {
+	internal := map[string]bool{root.Name: root.Internal}
+	var edges [][2]string
+	for from, to := range root.Edges {
+		internal[to.Name] = to.Internal
+		edges = append(edges, [2]string{from.Name, to.Name})
+	}
+
+	// Node IDs are assigned by the sorted index of each name, keeping them stable.
+	names := make([]string, 0, len(internal))
+	for name := range internal {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ids := make(map[string]string, len(names))
+	for idx, name := range names {
+		ids[name] = fmt.Sprintf("n%d", idx)
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="name" for="node" attr.name="name" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="internal" for="node" attr.name="internal" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <graph id="G" edgedefault="directed">` + "\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, `    <node id="%s"><data key="name">%s</data><data key="internal">%t</data></node>`+"\n",
+			ids[name], xmlEscape(name), internal[name])
+	}
+	for idx, e := range edges {
+		fmt.Fprintf(&b, `    <edge id="e%d" source="%s" target="%s"/>`+"\n", idx, ids[e[0]], ids[e[1]])
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// xmlEscape returns the text provided with any XML special characters escaped.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package depth
+
+import "os"
+
+func ExampleNewGraphMLFormatter() {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings", Internal: true},
+		}},
+	}}
+
+	_ = NewGraphMLFormatter().Format(os.Stdout, &p)
+	// Output:
+	// <?xml version="1.0" encoding="UTF-8"?>
+	// <graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+	//   <key id="name" for="node" attr.name="name" attr.type="string"/>
+	//   <key id="internal" for="node" attr.name="internal" attr.type="boolean"/>
+	//   <graph id="G" edgedefault="directed">
+	//     <node id="n0"><data key="name">github.com/foo/bar</data><data key="internal">false</data></node>
+	//     <node id="n1"><data key="name">root</data><data key="internal">false</data></node>
+	//     <node id="n2"><data key="name">strings</data><data key="internal">true</data></node>
+	//     <edge id="e0" source="n0" target="n2"/>
+	//     <edge id="e1" source="n1" target="n0"/>
+	//     <edge id="e2" source="n1" target="n2"/>
+	//   </graph>
+	// </graphml>
+}
@@ -0,0 +1,87 @@
+package depth
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidImportPath is returned, wrapped with the details of the problem, when a Tree is
+// resolved with a malformed import path.
+var ErrInvalidImportPath = errors.New("invalid import path")
+
+// ValidateImportPath returns an error wrapping ErrInvalidImportPath if the name provided is
+// not a valid import path, following the rules of the go command. Relative paths, such as
+// "./cmd/depth", name a directory and are always considered valid.
+func ValidateImportPath(name string) error {
+	if build.IsLocalImport(name) {
+		return nil
+	}
+
+	if err := checkImportPath(name); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidImportPath, name, err)
+	}
+	return nil
+}
+
+// checkImportPath returns the reason the non-local import path provided is invalid, if any.
+func checkImportPath(name string) error {
+	if name == "" {
+		return errors.New("empty string")
+	}
+	if !utf8.ValidString(name) {
+		return errors.New("invalid UTF-8")
+	}
+	if strings.HasPrefix(name, "/") {
+		return errors.New("leading slash")
+	}
+	if strings.HasSuffix(name, "/") {
+		return errors.New("trailing slash")
+	}
+
+	for _, elem := range strings.Split(name, "/") {
+		if err := checkImportPathElem(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkImportPathElem returns the reason a single slash-separated element of an import path
+// is invalid, if any.
+func checkImportPathElem(elem string) error {
+	if elem == "" {
+		return errors.New("double slash")
+	}
+	if strings.Trim(elem, ".") == "" {
+		return fmt.Errorf("invalid path element %q", elem)
+	}
+	if elem[0] == '.' {
+		return fmt.Errorf("leading dot in path element %q", elem)
+	}
+	if elem[len(elem)-1] == '.' {
+		return fmt.Errorf("trailing dot in path element %q", elem)
+	}
+
+	for _, r := range elem {
+		if !importPathRuneOK(r) {
+			return fmt.Errorf("invalid char %q", r)
+		}
+	}
+	return nil
+}
+
+// importPathRuneOK returns true if the rune is allowed within an import path. Only ASCII
+// letters, digits and a limited set of punctuation are allowed.
+func importPathRuneOK(r rune) bool {
+	if r >= utf8.RuneSelf {
+		return false
+	}
+
+	return 'a' <= r && r <= 'z' ||
+		'A' <= r && r <= 'Z' ||
+		'0' <= r && r <= '9' ||
+		strings.ContainsRune("-._~+", r)
+}
@@ -0,0 +1,52 @@
+package depth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateImportPath(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"strings", true},
+		{"github.com/adapap/depth/cmd/depth", true},
+		{"gopkg.in/yaml.v3", true},
+		{"golang.org/x/tools/go/packages", true},
+		{"github.com/foo/bar_baz-qux~1+2", true},
+		{".", true},
+		{"./cmd/depth", true},
+		{"../", true},
+
+		{"", false},
+		{"/usr/lib/go", false},
+		{"github.com/foo/", false},
+		{"github.com//foo", false},
+		{"github.com/my package", false},
+		{`github.com\foo\bar`, false},
+		{"github.com/foo/.hidden", false},
+		{"github.com/foo/bar.", false},
+		{"github.com/../foo", false},
+		{"github.com/föö", false},
+	}
+
+	for _, tc := range tests {
+		err := ValidateImportPath(tc.name)
+		if tc.valid {
+			assert.NoError(t, err, tc.name)
+		} else if assert.Error(t, err, tc.name) {
+			assert.True(t, errors.Is(err, ErrInvalidImportPath), tc.name)
+		}
+	}
+}
+
+func TestTree_ResolveInvalidImportPath(t *testing.T) {
+	tr := Tree{Importer: MockImporter{}}
+
+	err := tr.Resolve("github.com/my package")
+	assert.EqualError(t, err, `invalid import path "github.com/my package": invalid char ' '`)
+	assert.Nil(t, tr.Root)
+}
@@ -0,0 +1,60 @@
+package depth
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// generatedRegexp matches the comment marking a file as generated, as described by
+// 'go help generate'.
+var generatedRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// countLines returns the number of lines in the Go files of the Pkg, skipping generated
+// files. Vendored packages are not counted if the Tree ignores them.
+func (p *Pkg) countLines() int {
+	if p.Raw == nil || p.Raw.Dir == "" {
+		return 0
+	}
+	if p.Tree.IgnoreVendor && isVendored(p.Raw.Dir) {
+		return 0
+	}
+
+	var lines int
+	for _, name := range p.Raw.GoFiles {
+		data, err := os.ReadFile(filepath.Join(p.Raw.Dir, name))
+		if err != nil || isGenerated(data) {
+			continue
+		}
+		lines += bytes.Count(data, []byte("\n"))
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			lines++
+		}
+	}
+	return lines
+}
+
+// isVendored returns true if the directory provided is within a vendor directory.
+func isVendored(dir string) bool {
+	return slices.Contains(strings.Split(filepath.ToSlash(dir), "/"), "vendor")
+}
+
+// isGenerated returns true if the source provided has a generated code comment before
+// its package clause.
+func isGenerated(src []byte) bool {
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if generatedRegexp.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
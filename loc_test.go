@@ -0,0 +1,41 @@
+package depth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_ResolveCountLOC(t *testing.T) {
+	tr := Tree{CountLOC: true}
+	assert.NoError(t, tr.Resolve("./testdata/loc"))
+
+	// The generated file is skipped.
+	assert.Equal(t, 5, tr.Root.LinesOfCode)
+	assert.Positive(t, tr.Root.Deps[0].LinesOfCode)
+
+	tr = Tree{}
+	assert.NoError(t, tr.Resolve("./testdata/loc"))
+	assert.Zero(t, tr.Root.LinesOfCode)
+}
+
+func Test_isGenerated(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"// Code generated by stringer. DO NOT EDIT.\n\npackage a\n", true},
+		{"// Copyright 2024\n\n// Code generated by hand. DO NOT EDIT.\npackage a\n", true},
+		{"package a\n\n// Code generated by stringer. DO NOT EDIT.\n", false},
+		{"// Code generated by stringer.\npackage a\n", false},
+		{"package a\n", false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, isGenerated([]byte(tc.src)), tc.src)
+	}
+}
+
+func Test_isVendored(t *testing.T) {
+	assert.True(t, isVendored("/src/app/vendor/github.com/org/lib"))
+	assert.False(t, isVendored("/src/app/vendors/lib"))
+}
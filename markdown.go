@@ -0,0 +1,64 @@
+package depth
+
+import (
+	"fmt"
+	"go/build"
+	"io"
+	"strings"
+)
+
+// markdownEscaper escapes the characters that have special meaning within Markdown text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`,
+)
+
+// markdownURLEscaper escapes the characters that would end a Markdown link destination.
+var markdownURLEscaper = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
+
+// NewMarkdownFormatter returns a Formatter writing the dependencies as nested Markdown lists,
+// with each package linking to its documentation, such as for a README.
+func NewMarkdownFormatter() Formatter {
+	return FormatterFunc(func(w io.Writer, root *Pkg) error {
+		var b strings.Builder
+		writeMarkdown(&b, *root, 0)
+		_, err := io.WriteString(w, b.String())
+		return err
+	})
+}
+
+// writeMarkdown recursively writes a Pkg and its dependencies as Markdown list items,
+// indented by the level provided.
+func writeMarkdown(b *strings.Builder, p Pkg, level int) {
+	prefix := strings.Repeat("  ", level) + "- "
+
+	// Omitted packages are placeholders rather than packages that can be linked to.
+	if p.Omitted > 0 {
+		fmt.Fprintf(b, "%v%v\n", prefix, markdownEscaper.Replace(p.Name))
+		return
+	}
+
+	// Timings would only add noise to documentation.
+	p.Elapsed = 0
+	text := markdownEscaper.Replace(p.String())
+
+	// Relative packages are linked by their import path, when known.
+	path := p.Name
+	if build.IsLocalImport(path) && p.Raw != nil {
+		path = p.Raw.ImportPath
+	}
+	if build.IsLocalImport(path) {
+		fmt.Fprintf(b, "%v%v\n", prefix, text)
+	} else {
+		name := markdownEscaper.Replace(p.Name)
+		fmt.Fprintf(b, "%v[%v](https://pkg.go.dev/%v)%v\n",
+			prefix,
+			name,
+			markdownURLEscaper.Replace(path),
+			strings.TrimPrefix(text, name))
+	}
+
+	for _, d := range p.Deps {
+		writeMarkdown(b, d, level+1)
+	}
+}
@@ -0,0 +1,21 @@
+package depth
+
+import "os"
+
+func ExampleNewMarkdownFormatter() {
+	p := Pkg{Name: "root", Resolved: true, Deps: []Pkg{
+		{Name: "strings", Internal: true, Resolved: true},
+		{Name: "github.com/foo/bar_baz", Deps: []Pkg{
+			{Name: "strings", Internal: true, Resolved: true},
+		}},
+		{Name: "... (2 more)", Omitted: 2},
+	}}
+
+	_ = NewMarkdownFormatter().Format(os.Stdout, &p)
+	// Output:
+	// - [root](https://pkg.go.dev/root)
+	//   - [strings](https://pkg.go.dev/strings)
+	//   - [github.com/foo/bar\_baz](https://pkg.go.dev/github.com/foo/bar_baz) (unresolved)
+	//     - [strings](https://pkg.go.dev/strings)
+	//   - ... (2 more)
+}
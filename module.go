@@ -0,0 +1,442 @@
+package depth
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/build"
+	"go/version"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ModuleMode determines whether the default Importer finds packages using modules, through
+// the go command, or within the GOPATH.
+type ModuleMode int
+
+const (
+	// ModuleModeAuto uses modules when a go.mod file is found in the working directory or
+	// any of its parents, and the GOPATH otherwise.
+	ModuleModeAuto ModuleMode = iota
+	// ModuleModeOn always uses modules.
+	ModuleModeOn
+	// ModuleModeOff always uses the GOPATH.
+	ModuleModeOff
+)
+
+// usesModules returns true if the default Importer of the Tree should use modules.
+func (t *Tree) usesModules() bool {
+	switch t.ModuleMode {
+	case ModuleModeOn:
+		return true
+	case ModuleModeOff:
+		return false
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	_, err = ParseModule(pwd)
+	return err == nil
+}
+
+// Module describes the Go module that a package belongs to.
+type Module struct {
+	// Path is the module path declared in the go.mod file.
+	Path string
+	// Version is the version of the module, or empty for modules outside of the module cache
+	// such as the main module.
+	Version string
+	// Dir is the root directory of the module, containing the go.mod file.
+	Dir string
+	// GoVersion is the version declared by the go directive of the go.mod file, such as 1.21.
+	GoVersion string
+	// Requires are the modules required by the go.mod file.
+	Requires []Requirement
+}
+
+// Requirement is a module required by a go.mod file.
+type Requirement struct {
+	Path    string
+	Version string
+	// Indirect is true when the requirement is marked "// indirect", since no package of
+	// the requiring module imports it directly.
+	Indirect bool
+}
+
+// ParseModule walks up from dir to the nearest go.mod file, and returns the Module it
+// declares along with its requirements.
+func ParseModule(dir string) (*Module, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for start := dir; ; {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			path := parseModulePath(data)
+			if path == "" {
+				return nil, fmt.Errorf("no module path declared in %s", filepath.Join(dir, "go.mod"))
+			}
+			return &Module{Path: path, Dir: dir, GoVersion: parseGoVersion(data), Requires: parseRequirements(data)}, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("no go.mod file found in %s or any parent directory", start)
+		}
+		dir = parent
+	}
+}
+
+// moduleForDir returns the Module containing the package directory provided, or nil if
+// the directory does not belong to a module.
+func (t *Tree) moduleForDir(dir string) *Module {
+	t.Mutex.Lock()
+	mod, ok := t.moduleCache[dir]
+	t.Mutex.Unlock()
+	if ok {
+		return mod
+	}
+
+	// The go.mod file is read without holding the lock, so that packages imported
+	// concurrently aren't held up. Should another import find the module first, its
+	// Module is kept.
+	mod = findModule(dir)
+
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if cached, ok := t.moduleCache[dir]; ok {
+		return cached
+	}
+	if t.moduleCache == nil {
+		t.moduleCache = make(map[string]*Module)
+	}
+	t.moduleCache[dir] = mod
+	return mod
+}
+
+// findModule walks up from dir to the nearest go.mod file and returns the Module it declares.
+//
+// Vendored packages are not attributed to the module that vendors them, so nil is returned
+// if a vendor directory is encountered before a go.mod file.
+func findModule(dir string) *Module {
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+			path := parseModulePath(data)
+			if path == "" {
+				return nil
+			}
+			return &Module{
+				Path:      path,
+				Version:   moduleCacheVersion(dir),
+				Dir:       dir,
+				GoVersion: parseGoVersion(data),
+				Requires:  parseRequirements(data),
+			}
+		}
+
+		// Modules in the cache aren't required to have a go.mod file, but their
+		// directory always identifies them.
+		if mod := moduleCacheModule(dir); mod != nil {
+			return mod
+		}
+
+		if filepath.Base(dir) == "vendor" {
+			return nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+// parseModulePath returns the module path declared by the contents of a go.mod file.
+func parseModulePath(data []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// parseGoVersion returns the version declared by the go directive of the contents of a
+// go.mod file.
+func parseGoVersion(data []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) >= 2 && fields[0] == "go" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// MinGoVersion returns the highest Go version declared by the go.mod files of the modules
+// of the resolved packages, which is the minimum version needed to build them all. An empty
+// string is returned if no module declares a version.
+func (t *Tree) MinGoVersion() string {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	var highest string
+	for _, mod := range t.moduleCache {
+		if mod == nil || !version.IsValid("go"+mod.GoVersion) {
+			continue
+		}
+		if highest == "" || version.Compare("go"+mod.GoVersion, "go"+highest) > 0 {
+			highest = mod.GoVersion
+		}
+	}
+	return highest
+}
+
+// VersionConflicts returns each module of the resolved packages that is seen at more than
+// one version, mapped to those versions from lowest to highest. The versions are those of
+// the modules themselves, and those required by the go.mod files of the resolved modules.
+// Even though only one version is selected for a build, the spread shows which modules are
+// pushing others to upgrade.
+func (t *Tree) VersionConflicts() map[string][]string {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	versions := make(map[string][]string)
+	add := func(path, version string) {
+		if version != "" && !slices.Contains(versions[path], version) {
+			versions[path] = append(versions[path], version)
+		}
+	}
+	for _, mod := range t.moduleCache {
+		if mod == nil {
+			continue
+		}
+		add(mod.Path, mod.Version)
+		for _, req := range mod.Requires {
+			add(req.Path, req.Version)
+		}
+	}
+
+	// Only modules of the resolved packages are of interest, rather than every requirement.
+	resolved := make(map[string]bool)
+	for _, mod := range t.moduleCache {
+		if mod != nil {
+			resolved[mod.Path] = true
+		}
+	}
+
+	conflicts := make(map[string][]string)
+	for path, vs := range versions {
+		if resolved[path] && len(vs) > 1 {
+			slices.SortFunc(vs, compareModuleVersions)
+			conflicts[path] = vs
+		}
+	}
+	return conflicts
+}
+
+// Modules returns the sorted, unique paths of the modules that the packages the Root depends
+// on belong to, other than the module of the Root itself. Since a module usually provides
+// many packages, this is a better measure of the number of dependencies than the packages.
+func (t *Tree) Modules() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	return t.Root.Modules()
+}
+
+// ModuleCount returns the number of Modules that the Root depends on.
+func (t *Tree) ModuleCount() int {
+	return len(t.Modules())
+}
+
+// Modules returns the sorted, unique paths of the modules that the dependencies of the Pkg
+// belong to, other than the module of the Pkg itself. Only packages resolved in module mode
+// belong to a module.
+func (p *Pkg) Modules() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if depth > 0 && dep.Module != "" && dep.Module != p.Module && !seen[dep.Module] {
+			seen[dep.Module] = true
+			paths = append(paths, dep.Module)
+		}
+		return true
+	})
+	sort.Strings(paths)
+	return paths
+}
+
+// compareModuleVersions compares two semantic versions of a module, such as v1.2.3, by their
+// major, minor and patch numbers, and then by the rest of the version.
+func compareModuleVersions(a, b string) int {
+	aCore, aRest := splitModuleVersion(a)
+	bCore, bRest := splitModuleVersion(b)
+	if c := slices.Compare(aCore, bCore); c != 0 {
+		return c
+	}
+
+	// A version without a pre-release suffix is higher than one with it.
+	switch {
+	case aRest == bRest:
+		return 0
+	case aRest == "":
+		return 1
+	case bRest == "":
+		return -1
+	}
+	return strings.Compare(aRest, bRest)
+}
+
+// splitModuleVersion returns the major, minor and patch numbers of a semantic version, and
+// the rest of the version following them.
+func splitModuleVersion(v string) ([]int, string) {
+	v = strings.TrimPrefix(v, "v")
+	end := strings.IndexAny(v, "-+")
+	if end < 0 {
+		end = len(v)
+	}
+
+	var core []int
+	for _, part := range strings.Split(v[:end], ".") {
+		n, _ := strconv.Atoi(part)
+		core = append(core, n)
+	}
+	return core, v[end:]
+}
+
+// parseRequirements returns the requirements declared by the contents of a go.mod file, in
+// both single-line and block form.
+func parseRequirements(data []byte) []Requirement {
+	var reqs []Requirement
+	inBlock := false
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line, comment, _ := strings.Cut(s.Text(), "//")
+		fields := strings.Fields(line)
+
+		switch {
+		case inBlock && len(fields) == 1 && fields[0] == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case len(fields) == 2 && fields[0] == "require" && fields[1] == "(":
+			inBlock = true
+			continue
+		case len(fields) > 0 && fields[0] == "require":
+			fields = fields[1:]
+		default:
+			continue
+		}
+
+		if len(fields) != 2 {
+			continue
+		}
+		reqs = append(reqs, Requirement{
+			Path:     strings.Trim(fields[0], `"`),
+			Version:  fields[1],
+			Indirect: strings.TrimSpace(comment) == "indirect",
+		})
+	}
+	return reqs
+}
+
+// moduleCacheModule returns the Module rooted at dir if it is a module directory within
+// the module cache.
+func moduleCacheModule(dir string) *Module {
+	version := moduleCacheVersion(dir)
+	if version == "" {
+		return nil
+	}
+
+	rel, _ := filepath.Rel(moduleCacheDir(), dir)
+	path := strings.TrimSuffix(filepath.ToSlash(rel), "@"+version)
+	return &Module{
+		Path:    unescapeModulePath(path),
+		Version: version,
+		Dir:     dir,
+	}
+}
+
+// moduleCacheDir returns the root directory of the module cache.
+func moduleCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	return filepath.Join(filepath.SplitList(build.Default.GOPATH)[0], "pkg", "mod")
+}
+
+// unescapeModulePath reverses the case-encoding used by the module cache, in which each
+// upper-case letter is stored as an exclamation mark followed by its lower-case form.
+func unescapeModulePath(path string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range path {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// moduleCacheVersion returns the version of a module rooted at dir within the module cache,
+// which stores each module in a directory named path@version. Directories outside of the
+// module cache have no version, even if their names contain an @.
+func moduleCacheVersion(dir string) string {
+	rel, err := filepath.Rel(moduleCacheDir(), dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	base := filepath.Base(dir)
+	if idx := strings.LastIndex(base, "@"); idx >= 0 {
+		return base[idx+1:]
+	}
+	return ""
+}
+
+// inMainModule returns true if the package named belongs to the main module of the Tree's
+// resolution.
+func (t *Tree) inMainModule(name string) bool {
+	prefix := t.MainModule()
+	return prefix != "" && (name == prefix || strings.HasPrefix(name, prefix+"/"))
+}
+
+// isExternal returns true if the package named belongs to neither the standard library nor
+// the main module.
+func (t *Tree) isExternal(name string) bool {
+	return !isStdlibPath(name) && !t.inMainModule(name)
+}
+
+// isStdlibPath returns true if the import path provided belongs to the standard library. Like
+// the go command, standard library packages are recognized by the first element of their
+// import path not containing a dot.
+func isStdlibPath(name string) bool {
+	first, _, _ := strings.Cut(name, "/")
+	return !strings.Contains(first, ".")
+}
@@ -0,0 +1,210 @@
+package depth
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseModulePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"module github.com/foo/bar\n\ngo 1.23\n", "github.com/foo/bar"},
+		{"// comment\nmodule \"github.com/foo/bar\"\n", "github.com/foo/bar"},
+		{"go 1.23\n", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, parseModulePath([]byte(tt.input)))
+	}
+}
+
+func TestUnescapeModulePath(t *testing.T) {
+	assert.Equal(t, "github.com/BurntSushi/toml", unescapeModulePath("github.com/!burnt!sushi/toml"))
+	assert.Equal(t, "github.com/foo/bar", unescapeModulePath("github.com/foo/bar"))
+}
+
+func TestFindModule(t *testing.T) {
+	pwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	mod := findModule(pwd)
+	if assert.NotNil(t, mod) {
+		assert.Equal(t, "github.com/adapap/depth", mod.Path)
+		assert.Equal(t, "", mod.Version)
+		assert.Equal(t, pwd, mod.Dir)
+		assert.Equal(t, "1.23.0", mod.GoVersion)
+	}
+}
+
+func TestFindModuleVersion(t *testing.T) {
+	cache, other := t.TempDir(), t.TempDir()
+	t.Setenv("GOMODCACHE", cache)
+
+	for _, root := range []string{cache, other} {
+		dir := filepath.Join(root, "github.com", "foo", "bar@v1.2.3")
+		assert.NoError(t, os.MkdirAll(dir, 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/foo/bar\n"), 0o644))
+	}
+
+	// Only modules within the module cache are versioned by their directory.
+	mod := findModule(filepath.Join(cache, "github.com", "foo", "bar@v1.2.3"))
+	if assert.NotNil(t, mod) {
+		assert.Equal(t, "v1.2.3", mod.Version)
+	}
+	mod = findModule(filepath.Join(other, "github.com", "foo", "bar@v1.2.3"))
+	if assert.NotNil(t, mod) {
+		assert.Equal(t, "github.com/foo/bar", mod.Path)
+		assert.Equal(t, "", mod.Version)
+	}
+}
+
+func TestParseRequirements(t *testing.T) {
+	data := []byte(`module github.com/foo/bar
+
+go 1.23
+
+require github.com/single/dep v1.0.0
+
+require (
+	github.com/direct/dep v1.2.3
+	"github.com/quoted/dep" v0.1.0 // a comment
+	github.com/indirect/dep v2.0.0+incompatible // indirect
+)
+
+replace github.com/direct/dep => ../dep
+`)
+
+	assert.Equal(t, []Requirement{
+		{Path: "github.com/single/dep", Version: "v1.0.0"},
+		{Path: "github.com/direct/dep", Version: "v1.2.3"},
+		{Path: "github.com/quoted/dep", Version: "v0.1.0"},
+		{Path: "github.com/indirect/dep", Version: "v2.0.0+incompatible", Indirect: true},
+	}, parseRequirements(data))
+}
+
+func TestParseModule(t *testing.T) {
+	pwd, err := os.Getwd()
+	assert.NoError(t, err)
+
+	mod, err := ParseModule(filepath.Join(pwd, "cmd", "depth"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "github.com/adapap/depth", mod.Path)
+		assert.Equal(t, pwd, mod.Dir)
+		if assert.NotEmpty(t, mod.Requires) {
+			assert.Equal(t, "github.com/stretchr/testify", mod.Requires[0].Path)
+			assert.False(t, mod.Requires[0].Indirect)
+		}
+	}
+
+	_, err = ParseModule(t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestTree_MinGoVersion(t *testing.T) {
+	dirs := make(map[string]string)
+	for name, goVersion := range map[string]string{"root": "1.21", "a": "1.22.3", "b": "1.9"} {
+		dir := t.TempDir()
+		mod := "module github.com/org/" + name + "\n\ngo " + goVersion + "\n"
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(mod), 0644))
+		dirs["github.com/org/"+name] = dir
+	}
+
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		var imports []string
+		if name == "github.com/org/root" {
+			imports = []string{"github.com/org/a", "github.com/org/b", "strings"}
+		}
+		return &build.Package{ImportPath: name, Dir: dirs[name], Imports: imports, Goroot: name == "strings"}, nil
+	}}}
+	assert.Equal(t, "", tr.MinGoVersion())
+
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+	// Versions are compared numerically rather than as strings.
+	assert.Equal(t, "1.22.3", tr.MinGoVersion())
+}
+
+func TestTree_VersionConflicts(t *testing.T) {
+	base := t.TempDir()
+	mods := map[string]string{
+		"root":      "require (\n\tgithub.com/org/a v1.2.0\n\tgithub.com/org/c v1.9.0\n)\n",
+		"a@v1.2.0":  "",
+		"b@v1.0.0":  "require github.com/org/a v1.1.0\nrequire github.com/org/c v1.10.0\nrequire github.com/org/d v1.0.0\n",
+		"c@v1.10.0": "",
+	}
+	dirs := make(map[string]string)
+	for dir, requires := range mods {
+		name, _, _ := strings.Cut(dir, "@")
+		assert.NoError(t, os.MkdirAll(filepath.Join(base, dir), 0755))
+		mod := "module github.com/org/" + name + "\n\n" + requires
+		assert.NoError(t, os.WriteFile(filepath.Join(base, dir, "go.mod"), []byte(mod), 0644))
+		dirs["github.com/org/"+name] = filepath.Join(base, dir)
+	}
+
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		var imports []string
+		if name == "github.com/org/root" {
+			imports = []string{"github.com/org/a", "github.com/org/b", "github.com/org/c"}
+		}
+		return &build.Package{ImportPath: name, Dir: dirs[name], Imports: imports}, nil
+	}}}
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+
+	// The requirement of d is ignored, since none of its packages are depended on.
+	assert.Equal(t, map[string][]string{
+		"github.com/org/a": {"v1.1.0", "v1.2.0"},
+		"github.com/org/c": {"v1.9.0", "v1.10.0"},
+	}, tr.VersionConflicts())
+}
+
+func TestTree_Modules(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.Modules())
+	assert.Equal(t, 0, tr.ModuleCount())
+
+	tr.Root = &Pkg{Name: "github.com/org/root", Module: "github.com/org/root", Deps: []Pkg{
+		{Name: "github.com/org/root/internal/a", Module: "github.com/org/root"},
+		{Name: "golang.org/x/mod/semver", Module: "golang.org/x/mod", Deps: []Pkg{
+			{Name: "strings", Internal: true},
+		}},
+		{Name: "golang.org/x/mod/modfile", Module: "golang.org/x/mod", Deps: []Pkg{
+			{Name: "github.com/foo/bar", Module: "github.com/foo/bar"},
+		}},
+	}}
+	assert.Equal(t, []string{"github.com/foo/bar", "golang.org/x/mod"}, tr.Modules())
+	assert.Equal(t, 2, tr.ModuleCount())
+}
+
+func TestCompareModuleVersions(t *testing.T) {
+	assert.Equal(t, -1, compareModuleVersions("v1.9.0", "v1.10.0"))
+	assert.Equal(t, 1, compareModuleVersions("v2.0.0", "v1.10.0"))
+	assert.Equal(t, -1, compareModuleVersions("v1.0.0-rc.1", "v1.0.0"))
+	assert.Equal(t, -1, compareModuleVersions("v0.0.0-20161208181325-20d25e280405", "v0.0.0-20200101000000-abcdefabcdef"))
+	assert.Equal(t, 0, compareModuleVersions("v1.2.3", "v1.2.3"))
+}
+
+func TestTree_usesModules(t *testing.T) {
+	// The tests run within the module, so modules are detected.
+	assert.True(t, (&Tree{}).usesModules())
+	assert.Nil(t, (&Tree{}).BuildContext().JoinPath)
+	assert.False(t, (&Tree{ModuleMode: ModuleModeOff}).usesModules())
+	assert.NotNil(t, (&Tree{ModuleMode: ModuleModeOff}).BuildContext().JoinPath)
+
+	pwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(pwd)
+
+	assert.False(t, (&Tree{}).usesModules())
+	assert.True(t, (&Tree{ModuleMode: ModuleModeOn}).usesModules())
+
+	// Standard library packages are found either way.
+	tr := Tree{}
+	assert.NoError(t, tr.Resolve("strings"))
+}
@@ -0,0 +1,72 @@
+package depth
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+// majorVersionSuffix matches the final element of a module path at a major version, such as v2.
+var majorVersionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// expectedPkgName returns the name conventionally declared by the package at the import path,
+// which is its last element, skipping any major version suffix.
+func expectedPkgName(importPath string) string {
+	dir, name := path.Split(path.Clean(importPath))
+	if majorVersionSuffix.MatchString(name) && dir != "" {
+		name = path.Base(dir)
+	}
+	return name
+}
+
+// packageNamed returns a copy of a package whose directory contains the files of several
+// packages, as reported by a build.MultiplePackageError, limited to the files declaring the
+// package named, and its external tests. False is returned if no file declares the package.
+func packageNamed(pkg *build.Package, name string) (*build.Package, bool) {
+	fset := token.NewFileSet()
+	keep := make(map[string]bool)
+	for _, file := range slices.Concat(pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles, pkg.InvalidGoFiles) {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.PackageClauseOnly)
+		if err == nil && (f.Name.Name == name || f.Name.Name == name+"_test") {
+			keep[file] = true
+		}
+	}
+	if len(keep) == 0 {
+		return nil, false
+	}
+
+	c := *pkg
+	c.Name = name
+	c.InvalidGoFiles = nil
+	c.GoFiles = slices.DeleteFunc(slices.Clone(pkg.GoFiles), func(file string) bool { return !keep[file] })
+	c.CgoFiles = slices.DeleteFunc(slices.Clone(pkg.CgoFiles), func(file string) bool { return !keep[file] })
+	c.TestGoFiles = slices.DeleteFunc(slices.Clone(pkg.TestGoFiles), func(file string) bool { return !keep[file] })
+	c.XTestGoFiles = slices.DeleteFunc(slices.Clone(pkg.XTestGoFiles), func(file string) bool { return !keep[file] })
+	c.Imports, c.ImportPos = importsOfFiles(pkg.ImportPos, keep)
+	c.TestImports, c.TestImportPos = importsOfFiles(pkg.TestImportPos, keep)
+	c.XTestImports, c.XTestImportPos = importsOfFiles(pkg.XTestImportPos, keep)
+	return &c, true
+}
+
+// importsOfFiles returns the sorted imports, and their positions, made by the files to keep
+// among the positions of the imports provided.
+func importsOfFiles(positions map[string][]token.Position, keep map[string]bool) ([]string, map[string][]token.Position) {
+	var imports []string
+	kept := make(map[string][]token.Position)
+	for imp, poss := range positions {
+		for _, pos := range poss {
+			if keep[filepath.Base(pos.Filename)] {
+				kept[imp] = append(kept[imp], pos)
+			}
+		}
+		if len(kept[imp]) > 0 {
+			imports = append(imports, imp)
+		}
+	}
+	slices.Sort(imports)
+	return imports, kept
+}
@@ -0,0 +1,71 @@
+package depth
+
+import "slices"
+
+// DefaultNoisePackages are the low-level standard library packages hidden by a Tree with
+// HideInternalNoise or PruneNoise set, unless it provides its own NoisePackages. They are imported by
+// nearly every package, so they add little to a dependency tree.
+var DefaultNoisePackages = []string{
+	"unsafe",
+	"internal/abi",
+	"internal/asan",
+	"internal/bytealg",
+	"internal/byteorder",
+	"internal/chacha8rand",
+	"internal/coverage/rtcov",
+	"internal/cpu",
+	"internal/goarch",
+	"internal/godebugs",
+	"internal/goexperiment",
+	"internal/goos",
+	"internal/itoa",
+	"internal/msan",
+	"internal/profilerecord",
+	"internal/race",
+	"internal/runtime/atomic",
+	"internal/runtime/cgroup",
+	"internal/runtime/exithook",
+	"internal/runtime/gc",
+	"internal/runtime/gc/scan",
+	"internal/runtime/maps",
+	"internal/runtime/math",
+	"internal/runtime/pprof/label",
+	"internal/runtime/sys",
+	"internal/runtime/syscall",
+	"internal/runtime/syscall/linux",
+	"internal/stringslite",
+	"internal/sync",
+	"internal/synctest",
+	"internal/trace/tracev2",
+	"internal/unsafeheader",
+	"runtime/internal/atomic",
+	"runtime/internal/math",
+	"runtime/internal/sys",
+}
+
+// WithoutNoise returns a copy of the Pkg leaving out the noise packages hidden by its Tree,
+// along with everything beneath them. The Pkg itself is returned if the Tree hides none.
+func (p *Pkg) WithoutNoise() *Pkg {
+	if p.Tree == nil || !p.Tree.HideInternalNoise {
+		return p
+	}
+
+	c := p.without(func(dep *Pkg) bool {
+		return p.Tree.isNoise(dep.Name)
+	})
+	return &c
+}
+
+// isNoise returns true if the Tree hides or prunes noise packages, and the name provided is
+// one.
+func (t *Tree) isNoise(name string) bool {
+	if !t.HideInternalNoise && !t.PruneNoise {
+		return false
+	}
+
+	noise := t.NoisePackages
+	if noise == nil {
+		noise = DefaultNoisePackages
+	}
+	return slices.Contains(noise, name)
+}
@@ -0,0 +1,61 @@
+package depth
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_isNoise(t *testing.T) {
+	var tr Tree
+	assert.False(t, tr.isNoise("unsafe"))
+
+	tr.HideInternalNoise = true
+	assert.True(t, tr.isNoise("unsafe"))
+	assert.True(t, tr.isNoise("internal/bytealg"))
+	assert.False(t, tr.isNoise("strings"))
+
+	tr.NoisePackages = []string{"strings"}
+	assert.False(t, tr.isNoise("unsafe"))
+	assert.True(t, tr.isNoise("strings"))
+
+	tr = Tree{PruneNoise: true}
+	assert.True(t, tr.isNoise("unsafe"))
+}
+
+func noiseImporter() MockImporter {
+	return MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return &build.Package{ImportPath: name, Goroot: true, Imports: []string{"errors", "internal/bytealg", "unsafe"}}, nil
+	}}
+}
+
+func TestTree_ResolveHideInternalNoise(t *testing.T) {
+	tr := Tree{HideInternalNoise: true, Importer: noiseImporter()}
+	assert.NoError(t, tr.Resolve("strings"))
+
+	// The noise packages are resolved and counted, but left out of the output.
+	assert.Len(t, tr.Root.Deps, 3)
+	assert.Equal(t, 3, tr.Stats().Total)
+
+	root := tr.Root.WithoutNoise()
+	if assert.Len(t, root.Deps, 1) {
+		assert.Equal(t, "errors", root.Deps[0].Name)
+	}
+	assert.Len(t, tr.Root.Deps, 3)
+}
+
+func TestTree_ResolvePruneNoise(t *testing.T) {
+	tr := Tree{PruneNoise: true, Importer: noiseImporter()}
+	assert.NoError(t, tr.Resolve("strings"))
+
+	if assert.Len(t, tr.Root.Deps, 1) {
+		assert.Equal(t, "errors", tr.Root.Deps[0].Name)
+	}
+	assert.Equal(t, 1, tr.Stats().Total)
+
+	// Nothing is hidden unless HideInternalNoise is set.
+	tr = Tree{Importer: noiseImporter()}
+	assert.NoError(t, tr.Resolve("strings"))
+	assert.Same(t, tr.Root, tr.Root.WithoutNoise())
+}
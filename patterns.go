@@ -0,0 +1,106 @@
+package depth
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandPatterns returns the package names provided with any "..." wildcard patterns, such
+// as "./..." or "github.com/foo/bar/...", replaced by the packages they match. Like the go
+// command, a pattern matches the packages in the directory of its prefix and every directory
+// beneath it, excluding testdata, vendor and nested module directories, as well as
+// directories beginning with "." or "_". Directories are found, and their Go files matched,
+// with the build context provided, such as that returned by Tree.BuildContext, or with
+// build.Default if nil. Names without wildcards are returned as-is.
+func ExpandPatterns(ctx *build.Context, patterns []string) ([]string, error) {
+	if ctx == nil {
+		ctx = &build.Default
+	}
+
+
+	var names []string
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "...") {
+			names = append(names, pattern)
+			continue
+		}
+
+		matches, err := expandPattern(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+// expandPattern returns the packages matched by a single wildcard pattern with the build
+// context provided.
+func expandPattern(ctx *build.Context, pattern string) ([]string, error) {
+	base, ok := strings.CutSuffix(pattern, "/...")
+	if !ok || base == "" || strings.Contains(base, "...") {
+		return nil, fmt.Errorf("unsupported pattern %q, only a trailing /... is supported", pattern)
+	}
+
+	dir := base
+	if !build.IsLocalImport(base) {
+		pkg, err := ctx.Import(base, "", build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find %q for pattern %q: %w", base, pattern, err)
+		}
+		dir = pkg.Dir
+	}
+
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		if p != dir {
+			if name := d.Name(); name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		if _, err := ctx.ImportDir(p, 0); err != nil {
+			var noGo *build.NoGoError
+			if errors.As(err, &noGo) {
+				return nil
+			}
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, patternName(base, filepath.ToSlash(rel)))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// patternName returns the package name of the directory rel, relative to the directory of
+// the pattern base. Local names keep their leading "./" so they continue to be local.
+func patternName(base, rel string) string {
+	name := path.Join(base, rel)
+	if build.IsLocalImport(base) && !build.IsLocalImport(name) {
+		name = "./" + name
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package depth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandPatterns(t *testing.T) {
+	names, err := ExpandPatterns(nil, []string{"strings", "./..."})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"strings", ".", "./cmd/depth", "./gonumgraph", "./set", "./slicehelpers"}, names)
+
+	names, err = ExpandPatterns(nil, []string{"./set/..."})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"./set"}, names)
+
+	names, err = ExpandPatterns(nil, []string{"go/..."})
+	assert.NoError(t, err)
+	assert.Contains(t, names, "go/build")
+	assert.Contains(t, names, "go/build/constraint")
+	assert.NotContains(t, names, "go")
+
+	for _, pattern := range []string{"net/.../http", "...", "/...", "net..."} {
+		_, err = ExpandPatterns(nil, []string{pattern})
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestExpandPatterns_Skipped(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"a", "a/testdata", "a/_skip", "a/.hidden", "a/vendor/v", "nested", "nogo"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, sub), 0o755))
+	}
+	for _, f := range []string{"a/a.go", "a/testdata/t.go", "a/_skip/s.go", "a/.hidden/h.go", "a/vendor/v/v.go", "nested/n.go"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, f), []byte("package x\n"), 0o644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "go.mod"), []byte("module nested\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "nogo", "README"), nil, 0o644))
+
+	pwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(pwd)
+
+	names, err := ExpandPatterns(nil, []string{"./..."})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"./a"}, names)
+}
+
+func TestExpandPatterns_BuildContext(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "tagged"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "tagged", "t.go"), []byte("//go:build sometag\n\npackage x\n"), 0o644))
+
+	pwd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(pwd)
+
+	// Directories are only matched if their files are included by the build tags.
+	names, err := ExpandPatterns(nil, []string{"./..."})
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+
+	tr := Tree{BuildTags: []string{"sometag"}}
+	names, err = ExpandPatterns(tr.BuildContext(), []string{"./..."})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"./tagged"}, names)
+}
@@ -2,14 +2,18 @@ package depth
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"go/build"
+	"go/token"
 	"path"
-	"sort"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
-	
+
+	"github.com/adapap/depth/set"
 	"github.com/adapap/depth/slicehelpers"
 )
 
@@ -21,6 +25,69 @@ type Pkg struct {
 	Internal bool `json:"internal"`
 	Resolved bool `json:"resolved"`
 	Test     bool `json:"-"`
+	UsesCgo  bool `json:"usesCgo,omitempty"`
+
+	// Direct is true when the Pkg is imported by the non-test files of the Tree's Root.
+	Direct bool `json:"direct,omitempty"`
+
+	// AlsoTest is true when the Tree merges test dependencies, and the Pkg is imported by
+	// tests as well as by non-test files.
+	AlsoTest bool `json:"alsoTest,omitempty"`
+
+	// XTest is true when Test is, and the Pkg is imported by an external test package
+	// (package foo_test) rather than the tests of the package itself.
+	XTest bool `json:"-"`
+
+	// Module and Version identify the module that an external Pkg belongs to, when
+	// resolved in module mode.
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// BuildTags are the build tags appearing in the constraints of the files of the Pkg,
+	// whether or not they are satisfied, so the files, and imports, of a Pkg tagged this way
+	// may change with the BuildTags of the Tree.
+	BuildTags []string `json:"buildTags,omitempty"`
+
+	// NoGoFiles is true when the Pkg exists, but all of its Go files are excluded by the
+	// build context.
+	NoGoFiles bool `json:"noGoFiles,omitempty"`
+
+	// Omitted is the number of dependencies left out of the Deps of the parent Pkg due to
+	// the Tree's MaxBreadth, or by CollapseStdlibInternal. It is only set on the synthetic
+	// Pkg standing in for them.
+	Omitted int `json:"omitted,omitempty"`
+
+	// ImportedBy is the number of distinct packages in the tree importing the Pkg, as
+	// computed by Tree.ComputeFanIn.
+	ImportedBy int `json:"importedBy,omitempty"`
+
+	// MinDepth is the minimum depth at which the package appears anywhere in the tree, as
+	// computed by Tree.ComputeMinDepth.
+	MinDepth int `json:"minDepth,omitempty"`
+
+	// GraphHeight is the number of levels of dependencies beneath the package anywhere in
+	// the tree, as computed by Tree.ComputeHeight. Unlike Height, it includes the dependencies
+	// of repeated packages, which are only expanded once.
+	GraphHeight int `json:"height,omitempty"`
+
+	// IsCommand is true when the Pkg is a main package, producing an executable.
+	IsCommand bool `json:"isCommand,omitempty"`
+
+	// PotentiallyExported is true for direct dependencies of the Root imported by a file
+	// declaring exported identifiers, so their types may appear in its API. It's a heuristic
+	// based only on the files, rather than the signatures, of the Root.
+	PotentiallyExported bool `json:"potentiallyExported,omitempty"`
+
+	// LinesOfCode is the number of lines in the non-test Go files of the Pkg, excluding
+	// generated files, when the Tree counts them.
+	LinesOfCode int `json:"linesOfCode,omitempty"`
+
+	// Err is the error encountered importing the Pkg, when it could not be Resolved.
+	Err error `json:"-"`
+
+	// Truncated is true when the dependencies of the Pkg were not resolved because
+	// the Tree's Timeout elapsed or its MaxPackages was reached.
+	Truncated bool `json:"truncated,omitempty"`
 
 	Tree   *Tree `json:"-"`
 	Parent *Pkg  `json:"-"`
@@ -29,112 +96,395 @@ type Pkg struct {
 	Raw     *build.Package `json:"-"`
 	Elapsed time.Duration  `json:"-"`
 	Depth   int            `json:"-"`
+
+	// testRoot is true for the Pkg representing the external test package of the Tree's
+	// Root, when the Tree has SeparateTestRoots set.
+	testRoot bool
+	// synthetic is true for a Pkg that isn't imported, since its Raw is provided instead,
+	// as for a test root or the root of Tree.ResolveDir.
+	synthetic bool
+
+	// expanded is true when the dependencies of the Pkg were resolved, rather than it being
+	// left collapsed as a repeated, stdlib or truncated package.
+	expanded bool
+	// omittedDeps is the number of dependencies left out of the Deps due to the Tree's
+	// MaxBreadth, which are shown by a synthetic Pkg once the Deps are sorted.
+	omittedDeps int
 }
 
-func (p *Pkg) matchesPattern() bool {
-	if len(p.Tree.IncludePatterns) == 0 {
-		return true
+// MatchesPatterns reports whether name contains any of the include patterns and none of
+// the exclude patterns. If no include patterns are provided, every name not excluded matches.
+func MatchesPatterns(name string, include, exclude []string) bool {
+	contains := func(pattern string) bool {
+		return strings.Contains(name, pattern)
 	}
+	if len(include) > 0 && !slicehelpers.Any(include, contains) {
+		return false
+	}
+	return !slicehelpers.Any(exclude, contains)
+}
+
+func (p *Pkg) matchesPattern() bool {
+	return MatchesPatterns(p.Name, p.Tree.IncludePatterns, p.Tree.ExcludePatterns)
+}
+
+// Resolve recursively finds all dependencies for the Pkg and the packages it depends on.
+//
+// Dependencies are resolved one level of the tree at a time. Before a level is imported
+// concurrently, each of its packages claims its import path in order, so the first occurrence
+// of a package in breadth-first order is always the one to be expanded and every later
+// occurrence is left collapsed. This keeps the shape of the tree the same between runs,
+// regardless of the order in which the concurrent imports complete.
+//
+// The package imported for the first occurrence is memoized by name, and every later
+// occurrence reuses it rather than being imported again, so that each occurrence describes
+// the package identically. The dependencies of a package are only held by its expanded
+// occurrence, which Find returns.
+func (p *Pkg) Resolve(i Importer) {
+	p.resolve(i, nil)
+}
+
+// resolve resolves the Pkg as Resolve does, calling visit, if provided, with each package of
+// a level once it has been imported. If visit returns false, resolution stops, leaving the
+// dependencies of the level unresolved.
+func (p *Pkg) resolve(i Importer, visit func(p *Pkg) bool) {
+	visit = p.Tree.withProgress(visit)
+	level := []*Pkg{p}
+	for len(level) > 0 {
+		// Claiming happens sequentially to keep the outcome deterministic.
+		names := make([]string, len(level))
+		modes := make([]build.ImportMode, len(level))
+		repeated := make([]bool, len(level))
+		for idx, dep := range level {
+			names[idx], modes[idx], repeated[idx] = dep.importMode()
+		}
+
+		var wg sync.WaitGroup
+		expand := make([]bool, len(level))
+		for idx, dep := range level {
+			if dep.synthetic {
+				expand[idx] = true
+				continue
+			}
+			if names[idx] == "" || repeated[idx] {
+				continue
+			}
+
+			wg.Add(1)
+			go func(idx int, dep *Pkg) {
+				defer wg.Done()
+				expand[idx] = dep.importPkg(i, names[idx], modes[idx])
+			}(idx, dep)
+		}
+		wg.Wait()
+
+		// Repeated packages may have been claimed within the same level, so they're only
+		// reused once every package of the level has been imported.
+		for idx, dep := range level {
+			if names[idx] != "" && !repeated[idx] {
+				p.Tree.memoize(names[idx], dep)
+			}
+		}
+		for idx, dep := range level {
+			if first := p.Tree.memoized(names[idx]); repeated[idx] && first != nil {
+				dep.reuse(first)
+			}
+		}
+
+		var next []*Pkg
+		for idx, dep := range level {
+			if !expand[idx] {
+				continue
+			}
+			dep.expanded = true
+
+			// First we set the regular dependencies, then we add the test dependencies
+			// sharing the same set. This allows us to mark all test-only deps linearly
+			unique := make(map[string]struct{})
+			srcDir := dep.depsSrcDir()
+			if dep.testRoot {
+				dep.setDeps(dep.Raw.XTestImports, srcDir, unique, true, true)
+			} else {
+				dep.setDeps(dep.Raw.Imports, srcDir, unique, false, false)
+				if dep.Tree.ResolveTest {
+					dep.setDeps(dep.Raw.TestImports, srcDir, unique, true, false)
+				}
+				if dep.Tree.ResolveXTest && dep.Tree.SeparateTestRoots && dep == dep.Tree.Root {
+					if len(dep.Raw.XTestImports) > 0 {
+						dep.Deps = append(dep.Deps, dep.newTestRoot())
+					}
+				} else if dep.Tree.ResolveXTest {
+					dep.setDeps(dep.Raw.XTestImports, srcDir, unique, true, true)
+				}
+			}
 
-	// Split pattern by comma to handle multiple patterns.
-	for _, pattern := range p.Tree.IncludePatterns {
-		if strings.Contains(p.Name, pattern) {
-			if len(p.Tree.ExcludePatterns) == 0 {
-				return true
+			dep.limitDeps()
+			for j := range dep.Deps {
+				next = append(next, &dep.Deps[j])
 			}
-			return !slicehelpers.Any(p.Tree.ExcludePatterns, func(excludePattern string) bool {
-				return strings.Contains(p.Name, excludePattern)
-			})
 		}
+		if visit != nil {
+			for _, dep := range level {
+				if !visit(dep) {
+					next = nil
+					break
+				}
+			}
+		}
+		level = next
+	}
+
+	if p.Tree.MergeTestDeps {
+		p.mergeTestDeps()
 	}
-	return false
+	p.sortDeps()
 }
 
-// Resolve recursively finds all dependencies for the Pkg and the packages it depends on.
-func (p *Pkg) Resolve(i Importer) {
+// mergeTestDeps marks each occurrence of a package that is both a test and a non-test
+// dependency as a non-test dependency that is AlsoTest, so that it's only counted once.
+func (p *Pkg) mergeTestDeps() {
+	test, nonTest := set.New[string](), set.New[string]()
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if depth > 0 && dep.Test {
+			test.Add(dep.Name)
+		} else if depth > 0 {
+			nonTest.Add(dep.Name)
+		}
+		return true
+	})
+
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if depth > 0 && test.Has(dep.Name) && nonTest.Has(dep.Name) {
+			dep.Test, dep.XTest, dep.AlsoTest = false, false, true
+		}
+		return true
+	})
+}
+
+// importMode returns the cleaned name of the Pkg and the mode it should be imported with.
+// An empty name is returned if the Pkg should not be imported at all, and repeated is true if
+// the package was already claimed by an earlier occurrence, whose import should be reused.
+func (p *Pkg) importMode() (name string, mode build.ImportMode, repeated bool) {
 	// Resolved is always true, regardless of if we skip the import,
 	// it is only false if there is an error while importing.
 	p.Resolved = true
 
-	name := p.cleanName()
-	if name == "" || !p.matchesPattern() {
-		return
+	// A test root shares the package already imported for the Tree's Root, and the root of
+	// a directory is made up of the packages within it.
+	if p.synthetic {
+		return "", 0, false
+	}
+
+	// The patterns only filter dependencies, so the Root is always imported.
+	name = p.cleanName()
+	if name == "" || (p != p.Tree.Root && !p.matchesPattern()) {
+		p.Tree.debug("skipping import", "pkg", p.Name, "reason", "pattern")
+		return "", 0, false
+	}
+
+	if p.Tree.hasSeenImport(name) {
+		p.Tree.debug("skipping dependencies", "pkg", name, "reason", "duplicate")
+		return name, build.FindOnly, true
+	}
+
+	// Stop resolving imports if we've reached max depth.
+	var reason string
+	switch {
+	case p.Tree.isAtMaxDepth(p):
+		reason = "max depth"
+	case p.Tree.FindOnly && p != p.Tree.Root:
+		reason = "find only"
+	case p.Tree.StopAtExternal && p != p.Tree.Root && p.Tree.isExternal(name):
+		reason = "external"
+	case p != p.Tree.Root && matchesPolicy(name, p.Tree.OpaquePackages):
+		reason = "opaque"
+	case p.Tree.isPastDeadline() || p.Tree.isPastMaxPackages():
+		reason = "truncated"
+		p.Truncated = true
+	}
+
+	if reason == "" {
+		return name, 0, false
 	}
+	p.Tree.debug("skipping dependencies", "pkg", name, "reason", reason)
+	return name, build.FindOnly, false
+}
 
-	// Stop resolving imports if we've reached max depth or found a duplicate.
-	var importMode build.ImportMode
-	if p.Tree.hasSeenImport(name) || p.Tree.isAtMaxDepth(p) {
-		importMode = build.FindOnly
+// withProgress returns the visit function provided, wrapped to report the number of packages
+// visited to the Progress of the Tree, if set.
+func (t *Tree) withProgress(visit func(p *Pkg) bool) func(p *Pkg) bool {
+	if t.Progress == nil {
+		return visit
 	}
 
+	var resolved int
+	return func(p *Pkg) bool {
+		resolved++
+		t.Progress(resolved)
+		return visit == nil || visit(p)
+	}
+}
+
+// importPkg imports the Pkg by name using the ImportMode provided, and returns true if its
+// dependencies should be resolved.
+func (p *Pkg) importPkg(i Importer, name string, importMode build.ImportMode) bool {
 	start := time.Now()
 	pkg, err := i.Import(name, p.SrcDir, importMode)
 	p.Elapsed = time.Since(start)
+	p.Tree.debug("imported", "pkg", name, "srcDir", p.SrcDir, "findOnly", importMode == build.FindOnly, "elapsed", p.Elapsed)
+
+	// A package without any buildable Go files still exists, it simply has no dependencies
+	// in this build context.
+	var noGoErr *build.NoGoError
+	if errors.As(err, &noGoErr) {
+		p.Tree.debug("skipping dependencies", "pkg", name, "reason", "no Go files")
+		p.NoGoFiles = true
+		if pkg != nil {
+			p.Raw = pkg
+			p.Name = pkg.ImportPath
+			p.BuildTags = pkg.AllTags
+		}
+		return false
+	}
+	// A directory containing files of several packages is still usable if one of them is the
+	// package expected at its import path, as the others are usually ignored by mistake.
+	var multiErr *build.MultiplePackageError
+	if errors.As(err, &multiErr) && pkg != nil {
+		expected := expectedPkgName(name)
+		if pkg.ImportPath != "" && !build.IsLocalImport(pkg.ImportPath) {
+			expected = expectedPkgName(pkg.ImportPath)
+		}
+		if named, ok := packageNamed(pkg, expected); ok {
+			p.Tree.debug("ignoring files of other packages", "pkg", name, "packages", multiErr.Packages)
+			pkg, err = named, nil
+		} else {
+			err = fmt.Errorf("directory contains multiple packages: %s: %w", strings.Join(multiErr.Packages, ", "), err)
+		}
+	}
 	if err != nil {
-		// TODO: Check the error type?
+		p.Tree.debug("import failed", "pkg", name, "error", err)
 		p.Resolved = false
-		return
+		p.Err = err
+		return false
 	}
 	p.Raw = pkg
+	p.UsesCgo = len(pkg.CgoFiles) > 0 || slices.Contains(pkg.Imports, "C")
+	p.BuildTags = pkg.AllTags
+	p.IsCommand = pkg.IsCommand()
 
 	// Update the name with the fully qualified import path.
 	p.Name = pkg.ImportPath
 
-	// If this is an internal dependency, we may need to skip it.
-	if pkg.Goroot {
-		p.Internal = true
-		if !p.Tree.shouldResolveInternal(p) {
-			return
+	if !pkg.Goroot && pkg.Dir != "" {
+		if mod := p.Tree.moduleForDir(pkg.Dir); mod != nil {
+			p.Module = mod.Path
+			p.Version = mod.Version
 		}
 	}
 
-	// First we set the regular dependencies, then we add the test dependencies
-	// sharing the same set. This allows us to mark all test-only deps linearly
-	unique := make(map[string]struct{})
-	p.setDeps(i, pkg.Imports, pkg.Dir, unique, false)
-	if p.Tree.ResolveTest {
-		p.setDeps(i, append(pkg.TestImports, pkg.XTestImports...), pkg.Dir, unique, true)
+	p.Internal = pkg.Goroot
+	if p.Tree.InternalFunc != nil {
+		p.Internal = p.Tree.InternalFunc(p)
 	}
+
+	if p.Tree.CountLOC {
+		p.LinesOfCode = p.countLines()
+	}
+
+	// If this is a stdlib dependency, or another internal one decided by the ResolveInternalFunc,
+	// we may need to skip it.
+	internal := pkg.Goroot || (p.Tree.ResolveInternalFunc != nil && p.Internal)
+	if internal && !p.Tree.shouldResolveInternal(p) {
+		p.Tree.debug("skipping dependencies", "pkg", name, "reason", "internal")
+		return false
+	}
+
+	return importMode != build.FindOnly
 }
 
-// setDeps takes a slice of import paths and the source directory they are relative to,
-// and creates the Deps of the Pkg. Each dependency is also further resolved prior to being added
-// to the Pkg.
-func (p *Pkg) setDeps(i Importer, imports []string, srcDir string, unique map[string]struct{}, isTest bool) {
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+// reuse sets the Pkg from the first occurrence of the same package, memoized when it was
+// imported, rather than importing it again.
+func (p *Pkg) reuse(first *Pkg) {
+	p.Name, p.Raw = first.Name, first.Raw
+	p.Resolved, p.Err, p.NoGoFiles = first.Resolved, first.Err, first.NoGoFiles
+	p.UsesCgo, p.BuildTags, p.IsCommand = first.UsesCgo, first.BuildTags, first.IsCommand
+	p.Module, p.Version, p.LinesOfCode = first.Module, first.Version, first.LinesOfCode
+
+	p.Internal = first.Internal
+	if p.Tree.InternalFunc != nil && p.Raw != nil {
+		p.Internal = p.Tree.InternalFunc(p)
+	}
+}
+
+// depsSrcDir returns the directory that the imports of the Pkg are relative to. Symlinks are
+// resolved unless disabled by the Tree, so that a directory reached through a symlink isn't
+// treated as a different source directory.
+func (p *Pkg) depsSrcDir() string {
+	dir := p.Raw.Dir
+	if dir == "" || p.Tree.NoFollowSymlinks {
+		return dir
+	}
+
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		return resolved
+	}
+	return dir
+}
 
+// setDeps takes a slice of import paths and the source directory they are relative to,
+// and adds them to the Deps of the Pkg. The dependencies are not resolved.
+func (p *Pkg) setDeps(imports []string, srcDir string, unique map[string]struct{}, isTest, isXTest bool) {
 	for _, imp := range imports {
 		// Mostly for testing files where cyclic imports are allowed.
 		if imp == p.Name {
 			continue
 		}
 
+		// The C pseudo-package is reported through UsesCgo rather than as a dependency.
+		if imp == "C" {
+			continue
+		}
+
 		// Skip duplicates.
 		if _, ok := unique[imp]; ok {
 			continue
 		}
 		unique[imp] = struct{}{}
 
-		wg.Add(1)
-		go func(imp string) {
-			defer wg.Done()
-			dep := p.addDepParallel(i, imp, srcDir, isTest)
-			if dep == nil {
-				return
-			}
-			mu.Lock()
+		if dep := p.newDep(imp, srcDir, isTest); dep != nil {
+			dep.XTest = isXTest
 			p.Deps = append(p.Deps, *dep)
-			mu.Unlock()
-		}(imp)
+		}
 	}
+}
 
-	wg.Wait()
-	sort.Sort(byInternalAndName(p.Deps))
+// newTestRoot creates the Pkg standing in for the external test package of the Pkg, sharing
+// its Raw package so that it's expanded with the XTestImports rather than imported again.
+func (p *Pkg) newTestRoot() Pkg {
+	return Pkg{
+		Name:      p.Name + " [test]",
+		SrcDir:    p.SrcDir,
+		Internal:  p.Internal,
+		Resolved:  true,
+		Test:      true,
+		XTest:     true,
+		Tree:      p.Tree,
+		Parent:    p,
+		Raw:       p.Raw,
+		Depth:     p.Depth + 1,
+		testRoot:  true,
+		synthetic: true,
+	}
 }
 
-// addDepParallel is a parallel-safe version of addDep that returns the created Pkg
-func (p *Pkg) addDepParallel(i Importer, name string, srcDir string, isTest bool) *Pkg {
+// newDep creates an unresolved dependency of the Pkg, or returns nil if the dependency
+// is filtered out by the patterns of the Tree or is pruned as noise.
+func (p *Pkg) newDep(name string, srcDir string, isTest bool) *Pkg {
+	if p.Tree.PruneNoise && p.Tree.isNoise(name) {
+		p.Tree.debug("skipping import", "pkg", name, "reason", "noise")
+		return nil
+	}
+
 	dep := Pkg{
 		Name:   name,
 		SrcDir: srcDir,
@@ -142,14 +492,261 @@ func (p *Pkg) addDepParallel(i Importer, name string, srcDir string, isTest bool
 		Parent: p,
 		Test:   isTest,
 		Depth:  p.Depth + 1,
+		Direct: p == p.Tree.Root && !isTest,
 	}
 	if !dep.matchesPattern() {
+		p.Tree.debug("skipping import", "pkg", name, "reason", "pattern")
 		return nil
 	}
-	dep.Resolve(i)
 	return &dep
 }
 
+// limitDeps limits the Deps of the Pkg to the MaxBreadth of the Tree before any of them are
+// imported, so the dependencies left out are never resolved, and can never be the occurrence
+// of a package chosen to be expanded. The Deps kept are the first in the order of the SortMode,
+// as far as it can be known before they are imported.
+func (p *Pkg) limitDeps() {
+	limit := p.Tree.MaxBreadth
+	if limit <= 0 || len(p.Deps) <= limit {
+		return
+	}
+
+	p.Tree.SortMode.sortImports(p.Deps)
+	p.omittedDeps = len(p.Deps) - limit
+	p.Deps = p.Deps[:limit:limit]
+}
+
+// sortDeps recursively sorts the Deps of the Pkg according to the SortMode of the Tree,
+// followed by a Pkg noting how many were left out by limitDeps. Since sorting moves the
+// dependencies, their Parent is updated to match.
+func (p *Pkg) sortDeps() {
+	p.Tree.SortMode.sortDeps(p.Deps)
+	if p.omittedDeps > 0 {
+		p.Deps = append(p.Deps, Pkg{
+			Name:     fmt.Sprintf("... (%d more)", p.omittedDeps),
+			Tree:     p.Tree,
+			Resolved: true,
+			Depth:    p.Depth + 1,
+			Omitted:  p.omittedDeps,
+		})
+	}
+
+	for i := range p.Deps {
+		p.Deps[i].Parent = p
+		p.Deps[i].sortDeps()
+	}
+}
+
+// Walk performs a depth-first traversal of the Pkg and its dependencies, invoking fn for
+// each Pkg along with its depth relative to p. Returning false from fn prunes the
+// dependencies of that Pkg from the traversal.
+func (p *Pkg) Walk(fn func(p *Pkg, depth int) bool) {
+	p.walk(fn, 0)
+}
+
+func (p *Pkg) walk(fn func(p *Pkg, depth int) bool, depth int) {
+	if !fn(p, depth) {
+		return
+	}
+
+	for i := range p.Deps {
+		p.Deps[i].walk(fn, depth+1)
+	}
+}
+
+// WalkUnique is like Walk, but visits each Pkg name only once. Later occurrences of a
+// name that has already been visited are skipped along with their dependencies.
+func (p *Pkg) WalkUnique(fn func(p *Pkg, depth int) bool) {
+	seen := set.New[string]()
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if seen.Has(dep.Name) {
+			return false
+		}
+		seen.Add(dep.Name)
+		return fn(dep, depth)
+	})
+}
+
+// WalkPackages is like WalkUnique, but treats the occurrences of each package as a single
+// node: when a name is first reached, fn is invoked with the occurrence whose dependencies
+// were resolved, along with its depth relative to p, and the traversal continues with its
+// dependencies. Unlike WalkUnique, the dependencies of every package are walked, even when
+// the occurrence expanded during resolution isn't the first one reached depth-first.
+func (p *Pkg) WalkPackages(fn func(p *Pkg, depth int) bool) {
+	type occurrence struct {
+		pkg   *Pkg
+		depth int
+	}
+	expanded := make(map[string]occurrence)
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if prev, ok := expanded[dep.Name]; !ok || (!prev.pkg.expanded && dep.expanded) {
+			expanded[dep.Name] = occurrence{dep, depth}
+		}
+		return true
+	})
+
+	seen := set.New[string]()
+	var walk func(dep *Pkg)
+	walk = func(dep *Pkg) {
+		if seen.Has(dep.Name) {
+			return
+		}
+		seen.Add(dep.Name)
+
+		o := expanded[dep.Name]
+		if !fn(o.pkg, o.depth) {
+			return
+		}
+		for i := range o.pkg.Deps {
+			walk(&o.pkg.Deps[i])
+		}
+	}
+	walk(p)
+}
+
+// Edges iterates over each unique import within the Pkg and its dependencies, yielding the
+// importing Pkg and the imported Pkg. Edges is an iter.Seq2, so it can be ranged over:
+//
+//	for from, to := range p.Edges {
+//		fmt.Println(from.Name, "->", to.Name)
+//	}
+func (p *Pkg) Edges(yield func(from, to *Pkg) bool) {
+	seen := set.New[[2]string]()
+	stopped := false
+	p.Walk(func(from *Pkg, depth int) bool {
+		if stopped {
+			return false
+		}
+
+		for i := range from.Deps {
+			to := &from.Deps[i]
+			edge := [2]string{from.Name, to.Name}
+			if seen.Has(edge) {
+				continue
+			}
+			seen.Add(edge)
+
+			if !yield(from, to) {
+				stopped = true
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Filter returns a copy of the Pkg that retains only the dependencies for which pred returns
+// true, along with the ancestors needed to reach them. The Pkg itself is always retained.
+func (p *Pkg) Filter(pred func(p *Pkg) bool) *Pkg {
+	c, _ := p.filter(pred)
+	return &c
+}
+
+// WithoutOmitted returns a copy of the Pkg without the synthetic Pkgs standing in for omitted
+// dependencies, such as those added due to the MaxBreadth of the Tree or by
+// CollapseStdlibInternal, so that only real packages remain.
+func (p *Pkg) WithoutOmitted() *Pkg {
+	c := p.withoutOmitted()
+	return &c
+}
+
+func (p *Pkg) withoutOmitted() Pkg {
+	return p.without(func(p *Pkg) bool {
+		return p.Omitted > 0
+	})
+}
+
+// without returns a copy of the Pkg leaving out the dependencies for which drop returns true,
+// along with everything beneath them.
+func (p *Pkg) without(drop func(p *Pkg) bool) Pkg {
+	c := *p
+	c.Deps = nil
+	for i := range p.Deps {
+		if !drop(&p.Deps[i]) {
+			c.Deps = append(c.Deps, p.Deps[i].without(drop))
+		}
+	}
+	return c
+}
+
+// Find returns the Pkg named within the tree of the Pkg, including the Pkg itself, or nil
+// if it isn't found. When the name occurs several times, the occurrence whose dependencies
+// were resolved is preferred, falling back to the first in the order of Walk.
+func (p *Pkg) Find(name string) *Pkg {
+	var found *Pkg
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if dep.Name == name && (found == nil || (!found.expanded && dep.expanded)) {
+			found = dep
+		}
+		return true
+	})
+	return found
+}
+
+// Prune returns a copy of the Pkg that retains only the dependencies whose names match the
+// include and exclude patterns, using the same matching as IncludePatterns and ExcludePatterns
+// on the Tree, along with the ancestors needed to reach them. This allows a tree resolved
+// once without patterns to be filtered again cheaply.
+func (p *Pkg) Prune(include, exclude []string) *Pkg {
+	return p.Filter(func(p *Pkg) bool {
+		return MatchesPatterns(p.Name, include, exclude)
+	})
+}
+
+// CollapseStdlibInternal returns a copy of the Pkg in which the internal and vendored
+// packages of the standard library imported by each package are replaced with a single
+// Pkg named "(stdlib internals)", with Omitted set to the number of packages it replaces.
+// Since these are implementation details of the standard library, hiding them leaves the
+// packages developers actually depend on.
+func (p *Pkg) CollapseStdlibInternal() *Pkg {
+	c := p.collapseStdlibInternal()
+	return &c
+}
+
+func (p *Pkg) collapseStdlibInternal() Pkg {
+	c := *p
+	c.Deps = nil
+
+	var collapsed int
+	for i := range p.Deps {
+		if p.Deps[i].isStdlibInternal() {
+			collapsed++
+			continue
+		}
+		c.Deps = append(c.Deps, p.Deps[i].collapseStdlibInternal())
+	}
+	if collapsed > 0 {
+		c.Deps = append(c.Deps, Pkg{
+			Name:     "(stdlib internals)",
+			Tree:     p.Tree,
+			Internal: true,
+			Resolved: true,
+			Depth:    p.Depth + 1,
+			Omitted:  collapsed,
+		})
+	}
+	return c
+}
+
+// isStdlibInternal returns true if the Pkg is an internal or vendored package of the
+// standard library, which can't be imported outside of it.
+func (p *Pkg) isStdlibInternal() bool {
+	return p.isStdlib() && (isInternalPath(p.Name) || strings.HasPrefix(p.Name, "vendor/"))
+}
+
+// filter returns a filtered copy of the Pkg, and whether the Pkg or any of its
+// dependencies satisfied pred.
+func (p *Pkg) filter(pred func(p *Pkg) bool) (Pkg, bool) {
+	c := *p
+	c.Deps = nil
+	for i := range p.Deps {
+		if dep, ok := p.Deps[i].filter(pred); ok {
+			c.Deps = append(c.Deps, dep)
+		}
+	}
+	return c, len(c.Deps) > 0 || pred(p)
+}
+
 // isParent goes recursively up the chain of Pkgs to determine if the name provided is ever a
 // parent of the current Pkg.
 func (p *Pkg) isParent(name string) bool {
@@ -164,6 +761,54 @@ func (p *Pkg) isParent(name string) bool {
 	return p.Parent.isParent(name)
 }
 
+// ImportedFrom returns the positions, as file:line, at which the Pkg imports the dependency
+// named. Test and external test files are only included when the Tree resolves them. File
+// names are relative to the directory of the Pkg. If the Pkg was not imported in full, nil
+// is returned.
+func (p *Pkg) ImportedFrom(dep string) []string {
+	if p.Raw == nil {
+		return nil
+	}
+
+	all := []map[string][]token.Position{p.Raw.ImportPos}
+	if p.Tree == nil || p.Tree.ResolveTest {
+		all = append(all, p.Raw.TestImportPos)
+	}
+	if p.Tree == nil || p.Tree.ResolveXTest {
+		all = append(all, p.Raw.XTestImportPos)
+	}
+
+	var positions []string
+	for _, imports := range all {
+		for _, pos := range imports[dep] {
+			positions = append(positions, fmt.Sprintf("%s:%d", filepath.Base(pos.Filename), pos.Line))
+		}
+	}
+	return positions
+}
+
+// isStdlib returns true if the Pkg is part of the standard library. Since Internal may be
+// customized by the Tree, it is only used when the Pkg was not imported.
+func (p *Pkg) isStdlib() bool {
+	if p.Raw != nil {
+		return p.Raw.Goroot
+	}
+	return p.Internal
+}
+
+// Height returns the number of levels of dependencies beneath the Pkg: zero for a Pkg
+// without Deps, and otherwise one more than the greatest Height of its Deps. Since repeated
+// packages aren't expanded, see GraphHeight for the height of the package itself.
+func (p *Pkg) Height() int {
+	var h int
+	for i := range p.Deps {
+		if dh := p.Deps[i].Height() + 1; dh > h {
+			h = dh
+		}
+	}
+	return h
+}
+
 // depth returns the depth of the Pkg within the Tree.
 func (p *Pkg) depth() int {
 	if p.Parent == nil {
@@ -199,10 +844,30 @@ func (p *Pkg) cleanName() string {
 func (p *Pkg) String() string {
 	b := bytes.NewBufferString(p.Name)
 
+	if p.Version != "" {
+		b.Write([]byte("@" + p.Version))
+	}
+
 	if !p.Resolved {
 		b.Write([]byte(" (unresolved)"))
 	}
 
+	if p.UsesCgo {
+		b.Write([]byte(" (cgo)"))
+	}
+
+	if p.AlsoTest {
+		b.Write([]byte(" (also test)"))
+	}
+
+	if p.Truncated {
+		b.Write([]byte(" (truncated)"))
+	}
+
+	if p.NoGoFiles {
+		b.Write([]byte(" (no Go files for this build context)"))
+	}
+
 	if p.Elapsed > 0 {
 		b.Write([]byte(fmt.Sprintf(" (%s)", p.Elapsed)))
 	}
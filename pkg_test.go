@@ -1,9 +1,15 @@
 package depth
 
 import (
+	"fmt"
 	"go/build"
+	"go/token"
+	"os"
+	"path/filepath"
 	"sort"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPkg_CleanName(t *testing.T) {
@@ -53,11 +59,316 @@ func TestPkg_AddDepImportSeen(t *testing.T) {
 	}
 
 	// Hasn't seen the import
-	p.addDep(m, testName, testSrcDir, false)
+	p.newDep(testName, testSrcDir, false).Resolve(m)
+
+	// Has seen the import, so the earlier import is reused
+	m.ImportFn = func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		t.Fatalf("Unexpected import of a package already seen, name=%v", name)
+		return nil, nil
+	}
+	dep := p.newDep(testName, testSrcDir, false)
+	dep.Resolve(m)
+	if !dep.Resolved {
+		t.Fatalf("Expected the reused import to be resolved")
+	}
+}
+
+func TestPkg_ResolveNoGoFiles(t *testing.T) {
+	m := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return &build.Package{ImportPath: "github.com/foo/bar"}, &build.NoGoError{Dir: srcDir}
+	}}
+	p := Pkg{Name: "bar", Tree: &Tree{}}
+
+	p.Resolve(m)
+	assert.True(t, p.Resolved)
+	assert.True(t, p.NoGoFiles)
+	assert.Equal(t, "github.com/foo/bar", p.Name)
+	assert.Empty(t, p.Deps)
+}
+
+func TestPkg_ResolveSymlinks(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	assert.NoError(t, err)
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "link")
+	assert.NoError(t, os.Mkdir(target, 0755))
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("Unable to create symlink: %v", err)
+	}
+
+	m := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		if name == "root" {
+			return &build.Package{ImportPath: name, Dir: link, Imports: []string{"dep"}}, nil
+		}
+		return &build.Package{ImportPath: name}, nil
+	}}
+
+	for _, tc := range []struct {
+		noFollow bool
+		expected string
+	}{
+		{false, target},
+		{true, link},
+	} {
+		p := Pkg{Name: "root", Tree: &Tree{NoFollowSymlinks: tc.noFollow}}
+		p.Resolve(m)
+		if assert.Len(t, p.Deps, 1) {
+			assert.Equal(t, tc.expected, p.Deps[0].SrcDir)
+		}
+	}
+}
+
+func TestPkg_Walk(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{{Name: "c"}}},
+		{Name: "b", Deps: []Pkg{{Name: "c"}, {Name: "d"}}},
+	}}
+
+	var visited []string
+	var depths []int
+	p.Walk(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		depths = append(depths, depth)
+		return true
+	})
+	assert.Equal(t, []string{"root", "a", "c", "b", "c", "d"}, visited)
+	assert.Equal(t, []int{0, 1, 2, 1, 2, 2}, depths)
+
+	// Returning false prunes the subtree.
+	visited = nil
+	p.Walk(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		return p.Name != "b"
+	})
+	assert.Equal(t, []string{"root", "a", "c", "b"}, visited)
+}
+
+func TestPkg_WalkUnique(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{{Name: "c", Deps: []Pkg{{Name: "e"}}}}},
+		{Name: "b", Deps: []Pkg{{Name: "c", Deps: []Pkg{{Name: "f"}}}, {Name: "d"}}},
+	}}
+
+	var visited []string
+	p.WalkUnique(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		return true
+	})
+	assert.Equal(t, []string{"root", "a", "c", "e", "b", "d"}, visited)
+
+	// Pruned packages are still considered visited.
+	visited = nil
+	p.WalkUnique(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		return p.Name != "a"
+	})
+	assert.Equal(t, []string{"root", "a", "b", "c", "f", "d"}, visited)
+}
+
+func TestPkg_WalkPackages(t *testing.T) {
+	// The second occurrence of c is the one expanded during resolution.
+	p := Pkg{Name: "root", expanded: true, Deps: []Pkg{
+		{Name: "a", expanded: true, Deps: []Pkg{{Name: "c"}}},
+		{Name: "b", expanded: true, Deps: []Pkg{{Name: "c", expanded: true, Deps: []Pkg{{Name: "e"}}}, {Name: "d"}}},
+	}}
+
+	var visited []string
+	var depths []int
+	p.WalkPackages(func(dep *Pkg, depth int) bool {
+		visited = append(visited, dep.Name)
+		depths = append(depths, depth)
+		if dep.Name == "c" {
+			assert.Same(t, &p.Deps[1].Deps[0], dep)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"root", "a", "c", "e", "b", "d"}, visited)
+	assert.Equal(t, []int{0, 1, 2, 3, 1, 2}, depths)
+
+	// Pruning a package prunes its dependencies wherever it occurs.
+	visited = nil
+	p.WalkPackages(func(dep *Pkg, depth int) bool {
+		visited = append(visited, dep.Name)
+		return dep.Name != "c"
+	})
+	assert.Equal(t, []string{"root", "a", "c", "b", "d"}, visited)
+
+	// Each package is visited at the depth of the occurrence expanded.
+	p.Deps[0].Deps[0].Deps = []Pkg{{Name: "c"}}
+	p.Deps[0].Deps[0].Name, p.Deps[0].Deps[0].expanded = "x", true
+	depths = nil
+	p.WalkPackages(func(dep *Pkg, depth int) bool {
+		depths = append(depths, depth)
+		return true
+	})
+	assert.Equal(t, []int{0, 1, 2, 2, 3, 1, 2}, depths)
+}
 
-	// Has seen the import
-	expectedIm = build.FindOnly
-	p.addDep(m, testName, testSrcDir, false)
+func TestPkg_Filter(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{{Name: "c"}, {Name: "d", Test: true}}},
+		{Name: "b"},
+		{Name: "e", Test: true, Deps: []Pkg{{Name: "f"}}},
+	}}
+
+	f := p.Filter(func(p *Pkg) bool {
+		return p.Test
+	})
+
+	var visited []string
+	f.Walk(func(p *Pkg, depth int) bool {
+		visited = append(visited, p.Name)
+		return true
+	})
+	assert.Equal(t, []string{"root", "a", "d", "e"}, visited)
+
+	// The original is left untouched.
+	assert.Len(t, p.Deps, 3)
+	assert.Len(t, p.Deps[0].Deps, 2)
+}
+
+func TestPkg_Find(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Depth: 1},
+		{Name: "b", Depth: 1, Deps: []Pkg{
+			{Name: "a", Depth: 2, expanded: true, Deps: []Pkg{{Name: "c", Depth: 3}}},
+		}},
+	}}
+
+	// The expanded occurrence is preferred over the first.
+	a := p.Find("a")
+	if a == nil || len(a.Deps) != 1 {
+		t.Fatalf("Unexpected Pkg found, expected expanded a, got=%v", a)
+	}
+	assert.Same(t, &p.Deps[1].Deps[0], a)
+	assert.Equal(t, 1, a.Stats().MaxDepth)
+
+	assert.Same(t, &p, p.Find("root"))
+	assert.Nil(t, p.Find("d"))
+}
+
+func TestPkg_Prune(t *testing.T) {
+	importer := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		imports := map[string][]string{
+			"github.com/org/root": {"github.com/org/a", "github.com/org/b", "strings"},
+			"github.com/org/a":    {"github.com/org/c", "fmt"},
+			"github.com/org/b":    {"github.com/org/c"},
+			"github.com/org/c":    {"strings"},
+		}
+		return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+	}}
+	flatten := func(p *Pkg) []string {
+		var names []string
+		p.WalkUnique(func(p *Pkg, depth int) bool {
+			names = append(names, p.Name)
+			return true
+		})
+		sort.Strings(names)
+		return names
+	}
+
+	unfiltered := Tree{ResolveInternal: true, Importer: importer}
+	assert.NoError(t, unfiltered.Resolve("github.com/org/root"))
+
+	tests := []struct {
+		include, exclude []string
+	}{
+		{include: []string{"github.com/org/"}},
+		{include: []string{"github.com/org/"}, exclude: []string{"/c"}},
+		{include: []string{"github.com/org/", "strings"}},
+	}
+	for _, tc := range tests {
+		filtered := Tree{ResolveInternal: true, Importer: importer, IncludePatterns: tc.include, ExcludePatterns: tc.exclude}
+		assert.NoError(t, filtered.Resolve("github.com/org/root"))
+		assert.Equal(t, flatten(filtered.Root), flatten(unfiltered.Root.Prune(tc.include, tc.exclude)), "include=%v exclude=%v", tc.include, tc.exclude)
+	}
+
+	// The original is left untouched.
+	assert.Len(t, unfiltered.Root.Deps, 3)
+}
+
+func TestPkg_ResolveTestPatterns(t *testing.T) {
+	importer := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		pkgs := map[string]*build.Package{
+			"github.com/org/root": {
+				Imports:      []string{"github.com/org/a"},
+				TestImports:  []string{"github.com/stretchr/testify/assert", "github.com/org/b"},
+				XTestImports: []string{"github.com/stretchr/testify/require"},
+			},
+			"github.com/org/a":                    {Imports: []string{"strings"}},
+			"github.com/org/b":                    {Imports: []string{"fmt"}},
+			"github.com/stretchr/testify/assert":  {Imports: []string{"github.com/org/c"}},
+			"github.com/stretchr/testify/require": {Imports: []string{"github.com/stretchr/testify/assert"}},
+			"github.com/org/c":                    {},
+		}
+		pkg := pkgs[name]
+		if pkg == nil {
+			pkg = &build.Package{Goroot: true}
+		}
+		pkg.ImportPath = name
+		return pkg, nil
+	}}
+
+	tr := Tree{ResolveTest: true, ResolveXTest: true, Importer: importer, ExcludePatterns: []string{"testify"}}
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+
+	var names []string
+	tr.Root.WalkUnique(func(p *Pkg, depth int) bool {
+		names = append(names, p.Name)
+		return true
+	})
+	sort.Strings(names)
+
+	// The excluded test frameworks are dropped along with the packages only they import,
+	// while the remaining test dependencies are still resolved.
+	assert.Equal(t, []string{"fmt", "github.com/org/a", "github.com/org/b", "github.com/org/root", "strings"}, names)
+
+	// The Root is resolved even when it doesn't match the patterns itself.
+	tr = Tree{ResolveTest: true, Importer: importer, IncludePatterns: []string{"org/a"}}
+	assert.NoError(t, tr.Resolve("github.com/org/root"))
+	if assert.Len(t, tr.Root.Deps, 1) {
+		assert.Equal(t, "github.com/org/a", tr.Root.Deps[0].Name)
+	}
+}
+
+func TestPkg_Edges(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{{Name: "c"}}},
+		{Name: "b", Deps: []Pkg{{Name: "a", Deps: []Pkg{{Name: "c"}}}}},
+	}}
+
+	var edges []string
+	for from, to := range p.Edges {
+		edges = append(edges, from.Name+"->"+to.Name)
+	}
+	assert.Equal(t, []string{"root->a", "root->b", "a->c", "b->a"}, edges)
+
+	// Iteration stops when yield returns false.
+	edges = nil
+	for from, to := range p.Edges {
+		edges = append(edges, from.Name+"->"+to.Name)
+		break
+	}
+	assert.Equal(t, []string{"root->a"}, edges)
+}
+
+func ExamplePkg_Edges() {
+	p := Pkg{Name: "github.com/foo/cmd", Deps: []Pkg{
+		{Name: "fmt", Internal: true},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings", Internal: true},
+			{Name: "github.com/foo/baz"},
+		}},
+	}}
+
+	var external int
+	for from, to := range p.Edges {
+		if !from.Internal && !to.Internal {
+			external++
+		}
+	}
+	fmt.Printf("%d edges between external packages\n", external)
+	// Output: 2 edges between external packages
 }
 
 func TestByInternalAndName(t *testing.T) {
@@ -77,3 +388,71 @@ func TestByInternalAndName(t *testing.T) {
 		}
 	}
 }
+
+func TestPkg_ImportedFrom(t *testing.T) {
+	var p Pkg
+	assert.Nil(t, p.ImportedFrom("strings"))
+
+	p.Raw = &build.Package{
+		ImportPos: map[string][]token.Position{
+			"strings": {{Filename: "/src/foo/a.go", Line: 4}, {Filename: "/src/foo/b.go", Line: 7}},
+		},
+		XTestImportPos: map[string][]token.Position{
+			"strings": {{Filename: "/src/foo/a_test.go", Line: 5}},
+			"testing": {{Filename: "/src/foo/a_test.go", Line: 6}},
+		},
+	}
+	assert.Equal(t, []string{"a.go:4", "b.go:7", "a_test.go:5"}, p.ImportedFrom("strings"))
+	assert.Nil(t, p.ImportedFrom("errors"))
+
+	// Test files are only included when the Tree resolves them.
+	p.Tree = &Tree{}
+	assert.Equal(t, []string{"a.go:4", "b.go:7"}, p.ImportedFrom("strings"))
+	assert.Nil(t, p.ImportedFrom("testing"))
+}
+
+func TestPkg_CollapseStdlibInternal(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "internal/bytealg", Internal: true},
+		{Name: "strings", Internal: true, Deps: []Pkg{
+			{Name: "internal/abi", Internal: true},
+			{Name: "internal/bytealg", Internal: true},
+			{Name: "unsafe", Internal: true},
+		}},
+		{Name: "github.com/foo/internal/bar"},
+	}}
+
+	c := p.CollapseStdlibInternal()
+	assert.Len(t, c.Deps, 3)
+	assert.Equal(t, "strings", c.Deps[0].Name)
+	assert.Equal(t, "github.com/foo/internal/bar", c.Deps[1].Name)
+	assert.Equal(t, "(stdlib internals)", c.Deps[2].Name)
+	assert.Equal(t, 1, c.Deps[2].Omitted)
+
+	assert.Len(t, c.Deps[0].Deps, 2)
+	assert.Equal(t, "unsafe", c.Deps[0].Deps[0].Name)
+	assert.Equal(t, 2, c.Deps[0].Deps[1].Omitted)
+
+	// The original tree is unmodified.
+	assert.Len(t, p.Deps[1].Deps, 3)
+}
+
+func TestPkg_WithoutOmitted(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{
+			{Name: "b"},
+			{Name: "... (2 more)", Omitted: 2},
+		}},
+		{Name: "(stdlib internals)", Omitted: 1},
+	}}
+
+	c := p.WithoutOmitted()
+	if assert.Len(t, c.Deps, 1) {
+		assert.Equal(t, "a", c.Deps[0].Name)
+		assert.Len(t, c.Deps[0].Deps, 1)
+	}
+
+	// The original tree is unmodified.
+	assert.Len(t, p.Deps, 2)
+	assert.Len(t, p.Deps[0].Deps, 2)
+}
@@ -0,0 +1,65 @@
+package depth
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// matchesPolicy returns true if name contains any of the patterns provided, or matches any
+// of them as a glob, as described by path.Match.
+func matchesPolicy(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Violations returns the sorted, unique names of the resolved external packages that the
+// Root depends on without matching any entry of the Allowlist. Packages in the standard
+// library or the main module, or considered Internal, are always allowed. If the Allowlist
+// is empty, there are no violations.
+func (t *Tree) Violations() []string {
+	if t.Root == nil || len(t.Allowlist) == 0 {
+		return nil
+	}
+
+	var names []string
+	t.Root.WalkPackages(func(p *Pkg, depth int) bool {
+		if depth == 0 || p.Omitted > 0 || !p.Resolved || p.Internal || p.isStdlib() {
+			return true
+		}
+		if !t.inMainModule(p.Name) && !matchesPolicy(p.Name, t.Allowlist) {
+			names = append(names, p.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// BannedFound returns each package the Root depends on that matches an entry of Banned,
+// mapped to the chains of imports through which it is reached, formatted like
+// "root -> a -> banned". If no banned package is found, an empty map is returned.
+func (t *Tree) BannedFound() map[string][]string {
+	found := make(map[string][]string)
+	if t.Root == nil || len(t.Banned) == 0 {
+		return found
+	}
+
+	t.Root.WalkPackages(func(p *Pkg, depth int) bool {
+		if depth == 0 || p.Omitted > 0 || !matchesPolicy(p.Name, t.Banned) {
+			return true
+		}
+		for _, path := range t.ExplainPaths(p.Name) {
+			found[p.Name] = append(found[p.Name], strings.Join(path, " -> "))
+		}
+		return true
+	})
+	return found
+}
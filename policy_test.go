@@ -0,0 +1,52 @@
+package depth
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_Violations(t *testing.T) {
+	tr := Tree{
+		ModulePrefix: "github.com/org/app",
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports := map[string][]string{
+				"github.com/org/app":     {"github.com/org/app/lib", "github.com/approved/a", "strings"},
+				"github.com/org/app/lib": {"github.com/approved/a/sub", "github.com/unvetted/b", "gopkg.in/yaml.v3"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name], Goroot: name == "strings"}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("github.com/org/app"))
+
+	// Without an allowlist, everything is permitted.
+	assert.Nil(t, tr.Violations())
+
+	tr.Allowlist = []string{"github.com/approved/"}
+	assert.Equal(t, []string{"github.com/unvetted/b", "gopkg.in/yaml.v3"}, tr.Violations())
+
+	tr.Allowlist = []string{"github.com/approved/", "gopkg.in/*"}
+	assert.Equal(t, []string{"github.com/unvetted/b"}, tr.Violations())
+}
+
+func TestTree_BannedFound(t *testing.T) {
+	tr := Tree{
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports := map[string][]string{
+				"root": {"a", "b"},
+				"a":    {"github.com/old/lib"},
+				"b":    {"a", "github.com/old/lib/sub"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	assert.NoError(t, tr.Resolve("root"))
+	assert.Empty(t, tr.BannedFound())
+
+	tr.Banned = []string{"github.com/old/lib"}
+	assert.Equal(t, map[string][]string{
+		"github.com/old/lib":     {"root -> a -> github.com/old/lib"},
+		"github.com/old/lib/sub": {"root -> b -> github.com/old/lib/sub"},
+	}, tr.BannedFound())
+}
@@ -0,0 +1,52 @@
+package depth
+
+import (
+	"go/build"
+	"slices"
+	"sync"
+)
+
+// ImportCall is a single import requested of an Importer.
+type ImportCall struct {
+	Path   string
+	SrcDir string
+	Mode   build.ImportMode
+}
+
+// RecordingImporter is an Importer that records each import requested of it, before
+// delegating to another Importer. This makes it possible to assert which packages a Tree
+// imports while resolving.
+//
+// Since the packages at each level of the tree are imported concurrently, the order of the
+// calls is only guaranteed between levels.
+type RecordingImporter struct {
+	// Importer imports the packages requested. If nil, build.Default is used.
+	Importer Importer
+
+	mu    sync.Mutex
+	calls []ImportCall
+}
+
+// NewRecordingImporter returns a RecordingImporter delegating to the Importer provided.
+func NewRecordingImporter(i Importer) *RecordingImporter {
+	return &RecordingImporter{Importer: i}
+}
+
+func (r *RecordingImporter) Import(path, srcDir string, mode build.ImportMode) (*build.Package, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, ImportCall{path, srcDir, mode})
+	r.mu.Unlock()
+
+	if r.Importer == nil {
+		return build.Default.Import(path, srcDir, mode)
+	}
+	return r.Importer.Import(path, srcDir, mode)
+}
+
+// Calls returns the imports requested so far, in the order they were made.
+func (r *RecordingImporter) Calls() []ImportCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.calls)
+}
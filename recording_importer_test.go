@@ -0,0 +1,39 @@
+package depth
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingImporter(t *testing.T) {
+	r := NewRecordingImporter(MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		if name == "root" {
+			return &build.Package{ImportPath: name, Imports: []string{"a"}}, nil
+		}
+		return &build.Package{ImportPath: name}, nil
+	}})
+	assert.Empty(t, r.Calls())
+
+	tr := Tree{Importer: r}
+	assert.NoError(t, tr.Resolve("root"))
+
+	calls := r.Calls()
+	if assert.Len(t, calls, 2) {
+		assert.Equal(t, "root", calls[0].Path)
+		assert.Equal(t, ImportCall{Path: "a", SrcDir: "", Mode: 0}, calls[1])
+	}
+
+	// The calls returned are a copy.
+	calls[0].Path = "changed"
+	assert.Equal(t, "root", r.Calls()[0].Path)
+}
+
+func TestRecordingImporter_Default(t *testing.T) {
+	var r RecordingImporter
+	pkg, err := r.Import("strings", "", build.FindOnly)
+	assert.NoError(t, err)
+	assert.Equal(t, "strings", pkg.ImportPath)
+	assert.Equal(t, []ImportCall{{Path: "strings", Mode: build.FindOnly}}, r.Calls())
+}
@@ -0,0 +1,90 @@
+package depth
+
+import (
+	"context"
+	"errors"
+	"go/build"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultBackoff is the delay before the first retry of a RetryingImporter without a Backoff.
+const defaultBackoff = 100 * time.Millisecond
+
+// transientErrors are fragments of the messages of errors that are likely to succeed when
+// retried, such as those returned by 'go list' when a module proxy can't be reached. Since
+// build.Import returns most errors as plain strings, they can't be identified by type.
+var transientErrors = []string{
+	"i/o timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporary failure",
+	"TLS handshake timeout",
+	"unexpected EOF",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// RetryingImporter is an Importer that retries imports failing with transient errors, such
+// as network timeouts, before delegating to another Importer. Errors indicating that a
+// package doesn't exist are returned immediately.
+type RetryingImporter struct {
+	// Importer imports the packages requested. If nil, build.Default is used.
+	Importer Importer
+	// Retries is the maximum number of times a failed import is retried.
+	Retries int
+	// Backoff is the delay before the first retry, which doubles with each subsequent retry.
+	// If zero, 100ms is used.
+	Backoff time.Duration
+}
+
+// NewRetryingImporter returns a RetryingImporter delegating to the Importer provided, and
+// retrying each failed import up to the number of times provided.
+func NewRetryingImporter(i Importer, retries int) *RetryingImporter {
+	return &RetryingImporter{Importer: i, Retries: retries}
+}
+
+func (r *RetryingImporter) Import(path, srcDir string, mode build.ImportMode) (*build.Package, error) {
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		pkg, err := r.importOnce(path, srcDir, mode)
+		if err == nil || attempt >= r.Retries || !isTransient(err) {
+			return pkg, err
+		}
+		time.Sleep(backoff << attempt)
+	}
+}
+
+func (r *RetryingImporter) importOnce(path, srcDir string, mode build.ImportMode) (*build.Package, error) {
+	if r.Importer == nil {
+		return build.Default.Import(path, srcDir, mode)
+	}
+	return r.Importer.Import(path, srcDir, mode)
+}
+
+// isTransient returns true if the import error provided is likely to succeed when retried.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	msg := err.Error()
+	for _, s := range transientErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
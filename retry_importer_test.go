@@ -0,0 +1,69 @@
+package depth
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryingImporter(t *testing.T) {
+	var calls int
+	r := RetryingImporter{
+		Retries: 3,
+		Backoff: time.Millisecond,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			calls++
+			if calls < 3 {
+				return nil, errors.New("github.com/foo/bar: Get \"https://proxy.golang.org/...\": dial tcp: i/o timeout")
+			}
+			return &build.Package{ImportPath: name}, nil
+		}},
+	}
+
+	pkg, err := r.Import("github.com/foo/bar", "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/foo/bar", pkg.ImportPath)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryingImporterGivesUp(t *testing.T) {
+	var calls int
+	r := RetryingImporter{
+		Retries: 2,
+		Backoff: time.Millisecond,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			calls++
+			return nil, os.ErrDeadlineExceeded
+		}},
+	}
+
+	_, err := r.Import("github.com/foo/bar", "", 0)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryingImporterNotFound(t *testing.T) {
+	var calls int
+	r := RetryingImporter{
+		Retries: 3,
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			calls++
+			return nil, fmt.Errorf("cannot find package %q in any of: ...", name)
+		}},
+	}
+
+	_, err := r.Import("github.com/foo/bar", "", 0)
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func Test_isTransient(t *testing.T) {
+	assert.True(t, isTransient(errors.New("read tcp: connection reset by peer")))
+	assert.True(t, isTransient(fmt.Errorf("import: %w", os.ErrDeadlineExceeded)))
+	assert.False(t, isTransient(errors.New(`no required module provides package github.com/foo/bar`)))
+}
@@ -3,6 +3,7 @@ package set
 type Set[T comparable] interface {
 	Add(T) Set[T]
 	Has(T) bool
+	Len() int
 }
 
 func New[T comparable](values ...T) Set[T] {
@@ -28,3 +29,7 @@ func (s *set[T]) Has(v T) bool {
 	_, ok := s.data[v]
 	return ok
 }
+
+func (s *set[T]) Len() int {
+	return len(s.data)
+}
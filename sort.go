@@ -0,0 +1,144 @@
+package depth
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortMode determines the order of the Deps of each Pkg.
+type SortMode int
+
+const (
+	// SortInternalFirst sorts internal packages above external packages, and then by name.
+	SortInternalFirst SortMode = iota
+	// SortAlphabetical sorts packages by name, regardless of whether they are internal.
+	SortAlphabetical
+	// SortByDepth sorts packages with the deepest dependency trees first, and then by name.
+	SortByDepth
+	// SortUnsorted preserves the order in which the packages are imported.
+	SortUnsorted
+)
+
+var sortModeNames = map[SortMode]string{
+	SortInternalFirst: "internal",
+	SortAlphabetical:  "alpha",
+	SortByDepth:       "depth",
+	SortUnsorted:      "none",
+}
+
+// String returns the name of the SortMode, as accepted by Set.
+func (s SortMode) String() string {
+	if name, ok := sortModeNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("SortMode(%d)", int(s))
+}
+
+// Set parses the name of a SortMode, allowing it to be used as a flag.Value.
+func (s *SortMode) Set(name string) error {
+	for mode, n := range sortModeNames {
+		if n == name {
+			*s = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sort mode %q", name)
+}
+
+// sortDeps sorts the provided slice of Pkgs according to the SortMode.
+func (s SortMode) sortDeps(deps []Pkg) {
+	switch s {
+	case SortAlphabetical:
+		sort.Sort(byName(deps))
+	case SortByDepth:
+		sort.Sort(newByHeightAndName(deps))
+	case SortUnsorted:
+		return
+	default:
+		sort.Sort(byInternalAndName(deps))
+	}
+}
+
+// sortImports sorts the provided slice of Pkgs, which have not been imported yet, as closely
+// to the SortMode as their import paths allow. Whether a package is internal isn't known until
+// it's imported, so standard library packages are recognized by their import paths instead,
+// and since their heights aren't known either, SortByDepth sorts them by name.
+func (s SortMode) sortImports(deps []Pkg) {
+	switch s {
+	case SortUnsorted:
+		return
+	case SortInternalFirst:
+		sort.Sort(byStdlibPathAndName(deps))
+	default:
+		sort.Sort(byName(deps))
+	}
+}
+
+// byName sorts a slice of Pkgs by name.
+type byName []Pkg
+
+func (b byName) Len() int {
+	return len(b)
+}
+
+func (b byName) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byName) Less(i, j int) bool {
+	return b[i].Name < b[j].Name
+}
+
+// byHeightAndName sorts a slice of Pkgs such that those with the deepest dependency
+// trees come first, falling back to their names. The Height of each Pkg is computed once
+// by newByHeightAndName, rather than on every comparison.
+type byHeightAndName struct {
+	deps    []Pkg
+	heights []int
+}
+
+func newByHeightAndName(deps []Pkg) byHeightAndName {
+	heights := make([]int, len(deps))
+	for i := range deps {
+		heights[i] = deps[i].Height()
+	}
+	return byHeightAndName{deps: deps, heights: heights}
+}
+
+func (b byHeightAndName) Len() int {
+	return len(b.deps)
+}
+
+func (b byHeightAndName) Swap(i, j int) {
+	b.deps[i], b.deps[j] = b.deps[j], b.deps[i]
+	b.heights[i], b.heights[j] = b.heights[j], b.heights[i]
+}
+
+func (b byHeightAndName) Less(i, j int) bool {
+	if hi, hj := b.heights[i], b.heights[j]; hi != hj {
+		return hi > hj
+	}
+
+	return b.deps[i].Name < b.deps[j].Name
+}
+
+// byStdlibPathAndName sorts a slice of Pkgs such that those whose import paths belong to the
+// standard library come first, falling back to their names.
+type byStdlibPathAndName []Pkg
+
+func (b byStdlibPathAndName) Len() int {
+	return len(b)
+}
+
+func (b byStdlibPathAndName) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byStdlibPathAndName) Less(i, j int) bool {
+	si, sj := isStdlibPath(b[i].Name), isStdlibPath(b[j].Name)
+	if si != sj {
+		return si
+	}
+
+	return b[i].Name < b[j].Name
+}
@@ -0,0 +1,70 @@
+package depth
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortMode_Set(t *testing.T) {
+	for _, mode := range []SortMode{SortInternalFirst, SortAlphabetical, SortByDepth, SortUnsorted} {
+		var s SortMode
+		assert.NoError(t, s.Set(mode.String()))
+		assert.Equal(t, mode, s)
+	}
+
+	s := SortByDepth
+	assert.EqualError(t, s.Set("height"), `unknown sort mode "height"`)
+	assert.Equal(t, SortByDepth, s)
+	assert.Equal(t, "SortMode(9)", SortMode(9).String())
+}
+
+func TestByHeightAndName(t *testing.T) {
+	pkgs := []Pkg{
+		{Name: "a"},
+		{Name: "c", Deps: []Pkg{{Name: "d"}}},
+		{Name: "b", Deps: []Pkg{{Name: "d", Deps: []Pkg{{Name: "e"}}}}},
+		{Name: "f", Deps: []Pkg{{Name: "d"}}},
+	}
+
+	sort.Sort(newByHeightAndName(pkgs))
+
+	var names []string
+	for _, p := range pkgs {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"b", "c", "f", "a"}, names)
+}
+
+func TestSortMode_sortDeps(t *testing.T) {
+	deps := func() []Pkg {
+		return []Pkg{
+			{Name: "github.com/foo/bar"},
+			{Name: "strings", Internal: true},
+			{Name: "bytes", Internal: true, Deps: []Pkg{{Name: "io"}}},
+		}
+	}
+	names := func(pkgs []Pkg) []string {
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		mode     SortMode
+		expected []string
+	}{
+		{SortInternalFirst, []string{"bytes", "strings", "github.com/foo/bar"}},
+		{SortAlphabetical, []string{"bytes", "github.com/foo/bar", "strings"}},
+		{SortByDepth, []string{"bytes", "github.com/foo/bar", "strings"}},
+		{SortUnsorted, []string{"github.com/foo/bar", "strings", "bytes"}},
+	}
+	for _, tc := range tests {
+		pkgs := deps()
+		tc.mode.sortDeps(pkgs)
+		assert.Equal(t, tc.expected, names(pkgs), tc.mode.String())
+	}
+}
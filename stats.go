@@ -0,0 +1,284 @@
+package depth
+
+import "sort"
+
+// Stats summarizes the unique dependencies beneath a Pkg.
+type Stats struct {
+	Total    int `json:"total"`
+	Internal int `json:"internal"`
+	External int `json:"external"`
+	Testing  int `json:"testing"`
+	MaxDepth int `json:"maxDepth"`
+}
+
+// Stats returns a summary of the unique dependencies of the Root Pkg.
+//
+// If the Tree has not been resolved, a zero Stats is returned.
+func (t *Tree) Stats() Stats {
+	if t.Root == nil {
+		return Stats{}
+	}
+
+	return t.Root.Stats()
+}
+
+// Stats returns a summary of the unique dependencies of the Pkg. Each dependency is
+// counted once, regardless of how many times it is imported.
+func (p *Pkg) Stats() Stats {
+	var s Stats
+	p.WalkPackages(func(dep *Pkg, depth int) bool {
+		// The Pkg is not a dependency of itself, and omitted dependencies are unknown.
+		if depth == 0 || dep.Omitted > 0 {
+			return true
+		}
+
+		s.Total++
+		if dep.Internal {
+			s.Internal++
+		} else {
+			s.External++
+		}
+		if dep.Test {
+			s.Testing++
+		}
+		if depth > s.MaxDepth {
+			s.MaxDepth = depth
+		}
+		return true
+	})
+	return s
+}
+
+// ThirdParty returns the number of unique dependencies of the Pkg outside of the standard
+// library. Unlike the External count of Stats, it isn't affected by the InternalFunc of the
+// Tree marking other packages as internal.
+func (p *Pkg) ThirdParty() int {
+	var count int
+	p.WalkPackages(func(dep *Pkg, depth int) bool {
+		if depth > 0 && dep.Omitted == 0 && !dep.isStdlib() {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// StdlibDeps returns the sorted, unique names of the standard library packages that the
+// Root depends on.
+func (t *Tree) StdlibDeps() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	var names []string
+	t.Root.WalkPackages(func(p *Pkg, depth int) bool {
+		if depth > 0 && p.isStdlib() {
+			names = append(names, p.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// DepthHistogram returns the number of unique packages at each depth beneath the Root.
+// Since a package may be imported at several depths, each is counted at the minimum depth
+// at which it appears.
+func (t *Tree) DepthHistogram() map[int]int {
+	if t.Root == nil {
+		return nil
+	}
+
+	hist := make(map[int]int)
+	for _, depth := range t.Root.minDepths() {
+		hist[depth]++
+	}
+	return hist
+}
+
+// FirstSeenDepth returns the minimum depth at which the package named appears in the tree,
+// with the Root at depth zero. Unlike the Depth of an individual Pkg, this doesn't depend on
+// which occurrence of the package is considered. If the package isn't found, -1 is returned.
+func (t *Tree) FirstSeenDepth(name string) int {
+	if t.Root == nil {
+		return -1
+	}
+	if name == t.Root.Name {
+		return 0
+	}
+
+	if depth, ok := t.Root.minDepths()[name]; ok {
+		return depth
+	}
+	return -1
+}
+
+// WouldTruncate returns the sorted, unique names of the packages in the tree that would be
+// left out by resolving it again with a MaxDepth of maxDepth. Packages at that depth are
+// still found, so only those appearing solely beneath it are cut off. Since it works on the
+// resolved tree, it's only accurate for a tree resolved with a greater MaxDepth, or none.
+// If maxDepth is zero or less, nothing would be left out and nil is returned.
+func (t *Tree) WouldTruncate(maxDepth int) []string {
+	if t.Root == nil || maxDepth <= 0 {
+		return nil
+	}
+
+	var names []string
+	for name, depth := range t.Root.minDepths() {
+		if depth > maxDepth {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ComputeMinDepth annotates every Pkg in the tree with the minimum depth at which its
+// package appears, as returned by FirstSeenDepth.
+func (t *Tree) ComputeMinDepth() {
+	if t.Root == nil {
+		return
+	}
+
+	depths := t.Root.minDepths()
+	t.Root.Walk(func(p *Pkg, depth int) bool {
+		p.MinDepth = depths[p.Name]
+		return true
+	})
+}
+
+// ComputeHeight annotates every Pkg in the tree with the GraphHeight of its package: the
+// length of the longest chain of imports beneath it, across every occurrence of each package
+// in the tree. Chains are cut short by import cycles, which only tests can introduce.
+func (t *Tree) ComputeHeight() {
+	if t.Root == nil {
+		return
+	}
+
+	g := t.Root.graph()
+	heights := make(map[string]int, len(g))
+	visiting := make(map[string]bool)
+	var height func(name string) int
+	height = func(name string) int {
+		if h, ok := heights[name]; ok {
+			return h
+		}
+		if visiting[name] {
+			return 0
+		}
+
+		visiting[name] = true
+		var h int
+		for _, dep := range g[name] {
+			h = max(h, height(dep)+1)
+		}
+		visiting[name] = false
+		heights[name] = h
+		return h
+	}
+
+	t.Root.Walk(func(p *Pkg, depth int) bool {
+		p.GraphHeight = height(p.Name)
+		return true
+	})
+}
+
+// minDepths returns the minimum depth beneath the Pkg at which each of its dependencies
+// appears, excluding omitted dependencies.
+func (p *Pkg) minDepths() map[string]int {
+	depths := make(map[string]int)
+	p.Walk(func(dep *Pkg, depth int) bool {
+		if depth == 0 || dep.Omitted > 0 {
+			return true
+		}
+
+		if d, ok := depths[dep.Name]; !ok || depth < d {
+			depths[dep.Name] = depth
+		}
+		return true
+	})
+	return depths
+}
+
+// DirectDeps returns the sorted names of the packages imported by the non-test files of the
+// Root, rather than only through its other dependencies.
+func (t *Tree) DirectDeps() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	var names []string
+	for _, dep := range t.Root.Deps {
+		if dep.Direct {
+			names = append(names, dep.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IndirectDeps returns the sorted, unique names of the packages the Root depends on that
+// are not DirectDeps, including those only imported by the Root's test files.
+func (t *Tree) IndirectDeps() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	direct := make(map[string]bool)
+	for _, name := range t.DirectDeps() {
+		direct[name] = true
+	}
+
+	var names []string
+	t.Root.WalkPackages(func(p *Pkg, depth int) bool {
+		if depth > 0 && p.Omitted == 0 && !direct[p.Name] {
+			names = append(names, p.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// Leaves returns the sorted, unique names of the packages beneath the Root that have no
+// dependencies of their own. Packages that were not expanded, such as those cut off by the
+// MaxDepth or stdlib packages when not resolving internal dependencies, only appear to have
+// no dependencies and are excluded.
+func (t *Tree) Leaves() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	t.Root.Walk(func(p *Pkg, depth int) bool {
+		if depth > 0 && p.expanded && len(p.Deps) == 0 && !seen[p.Name] {
+			seen[p.Name] = true
+			names = append(names, p.Name)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// SourceDirs returns the sorted, unique directories of the packages in the tree that were
+// imported, including the Root. These are the directories whose files determine the tree, so
+// they can be watched to know when it should be resolved again.
+func (t *Tree) SourceDirs() []string {
+	if t.Root == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var dirs []string
+	t.Root.Walk(func(p *Pkg, depth int) bool {
+		if p.Raw != nil && p.Raw.Dir != "" && !seen[p.Raw.Dir] {
+			seen[p.Raw.Dir] = true
+			dirs = append(dirs, p.Raw.Dir)
+		}
+		return true
+	})
+	sort.Strings(dirs)
+	return dirs
+}
@@ -0,0 +1,184 @@
+package depth
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPkg_Stats(t *testing.T) {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true, Depth: 1},
+		{Name: "github.com/foo/bar", Depth: 1, Deps: []Pkg{
+			{Name: "strings", Internal: true, Depth: 2},
+			{Name: "github.com/foo/baz", Test: true, Depth: 2},
+		}},
+	}}
+
+	assert.Equal(t, Stats{Total: 3, Internal: 1, External: 2, Testing: 1, MaxDepth: 2}, p.Stats())
+}
+
+func TestPkg_ThirdParty(t *testing.T) {
+	// The first-party package is marked internal, as by an InternalFunc, but isn't part of
+	// the standard library.
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true, Raw: &build.Package{Goroot: true}},
+		{Name: "github.com/org/root/util", Internal: true, Raw: &build.Package{}, Deps: []Pkg{
+			{Name: "github.com/foo/bar", Raw: &build.Package{}},
+			{Name: "strings", Internal: true, Raw: &build.Package{Goroot: true}},
+		}},
+		{Name: "... (2 more)", Omitted: 2},
+	}}
+
+	assert.Equal(t, 2, p.ThirdParty())
+	assert.Equal(t, 1, p.Stats().External)
+}
+
+func TestTree_Stats(t *testing.T) {
+	var tr Tree
+	assert.Equal(t, Stats{}, tr.Stats())
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{{Name: "strings", Internal: true, Depth: 1}}}
+	assert.Equal(t, Stats{Total: 1, Internal: 1, MaxDepth: 1}, tr.Stats())
+}
+
+func TestTree_StdlibDeps(t *testing.T) {
+	tr := Tree{Root: &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings", Internal: true},
+			{Name: "errors", Internal: true},
+		}},
+	}}}
+
+	assert.Equal(t, []string{"errors", "strings"}, tr.StdlibDeps())
+}
+
+func TestTree_DepthHistogram(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.DepthHistogram())
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings"},
+			{Name: "github.com/foo/baz", Deps: []Pkg{
+				{Name: "errors"},
+			}},
+		}},
+		{Name: "strings"},
+	}}
+
+	// strings is counted at depth 1, where it first appears, rather than depth 2.
+	assert.Equal(t, map[int]int{1: 2, 2: 1, 3: 1}, tr.DepthHistogram())
+}
+
+func TestTree_FirstSeenDepth(t *testing.T) {
+	var tr Tree
+	assert.Equal(t, -1, tr.FirstSeenDepth("strings"))
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings", Depth: 2},
+			{Name: "errors"},
+		}},
+		{Name: "strings", Depth: 1},
+	}}
+	assert.Equal(t, 0, tr.FirstSeenDepth("root"))
+	assert.Equal(t, 1, tr.FirstSeenDepth("strings"))
+	assert.Equal(t, 2, tr.FirstSeenDepth("errors"))
+	assert.Equal(t, -1, tr.FirstSeenDepth("notreal"))
+
+	// Every occurrence is annotated with the same depth.
+	tr.ComputeMinDepth()
+	assert.Equal(t, 1, tr.Root.Deps[0].Deps[0].MinDepth)
+	assert.Equal(t, 1, tr.Root.Deps[1].MinDepth)
+	assert.Equal(t, 0, tr.Root.MinDepth)
+}
+
+func TestTree_DirectDeps(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.DirectDeps())
+	assert.Nil(t, tr.IndirectDeps())
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Direct: true},
+		{Name: "github.com/foo/bar", Direct: true, Deps: []Pkg{
+			{Name: "strings"},
+			{Name: "errors"},
+		}},
+		{Name: "testing", Test: true, Deps: []Pkg{
+			{Name: "github.com/foo/bar"},
+		}},
+	}}
+
+	assert.Equal(t, []string{"github.com/foo/bar", "strings"}, tr.DirectDeps())
+	assert.Equal(t, []string{"errors", "testing"}, tr.IndirectDeps())
+}
+
+func TestTree_ComputeHeight(t *testing.T) {
+	tr := &Tree{Root: &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{
+			{Name: "c", Deps: []Pkg{{Name: "e"}}},
+		}},
+		{Name: "b", Deps: []Pkg{{Name: "c"}}},
+	}}}
+	tr.ComputeHeight()
+
+	assert.Equal(t, 3, tr.Root.GraphHeight)
+	assert.Equal(t, 2, tr.Root.Deps[0].GraphHeight)
+	assert.Equal(t, 0, tr.Root.Deps[0].Deps[0].Deps[0].GraphHeight)
+
+	// The repeated c isn't expanded beneath b, but is still counted.
+	assert.Equal(t, 1, tr.Root.Deps[1].Height())
+	assert.Equal(t, 2, tr.Root.Deps[1].GraphHeight)
+	assert.Equal(t, 1, tr.Root.Deps[1].Deps[0].GraphHeight)
+
+	// Placeholders of omitted dependencies don't add a level beneath c.
+	tr = &Tree{Root: &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "a", Deps: []Pkg{{Name: "c", Deps: []Pkg{{Name: "... (2 more)", Omitted: 2}}}}},
+	}}}
+	tr.ComputeHeight()
+	assert.Equal(t, 2, tr.Root.GraphHeight)
+	assert.Equal(t, 0, tr.Root.Deps[0].Deps[0].GraphHeight)
+
+	tr = breadthTree(t)
+	tr.ComputeHeight()
+	assert.Equal(t, 2, tr.Root.GraphHeight)
+	assert.Equal(t, 1, tr.Root.Deps[0].GraphHeight)
+}
+
+func TestTree_WouldTruncate(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.WouldTruncate(1))
+
+	tr.Root = &Pkg{Name: "root", Deps: []Pkg{
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings"},
+			{Name: "github.com/foo/baz", Deps: []Pkg{
+				{Name: "errors"},
+			}},
+		}},
+		{Name: "strings"},
+	}}
+
+	assert.Nil(t, tr.WouldTruncate(0))
+	// strings is also found at depth 1, so it isn't cut off.
+	assert.Equal(t, []string{"errors", "github.com/foo/baz"}, tr.WouldTruncate(1))
+	assert.Equal(t, []string{"errors"}, tr.WouldTruncate(2))
+	assert.Nil(t, tr.WouldTruncate(3))
+}
+
+func TestTree_SourceDirs(t *testing.T) {
+	var tr Tree
+	assert.Nil(t, tr.SourceDirs())
+
+	tr.Root = &Pkg{Name: "root", Raw: &build.Package{Dir: "/src/root"}, Deps: []Pkg{
+		{Name: "github.com/foo/bar", Raw: &build.Package{Dir: "/src/bar"}, Deps: []Pkg{
+			{Name: "strings", Raw: &build.Package{Dir: "/goroot/src/strings"}},
+		}},
+		{Name: "strings", Raw: &build.Package{Dir: "/goroot/src/strings"}},
+		{Name: "unresolved"},
+	}}
+	assert.Equal(t, []string{"/goroot/src/strings", "/src/bar", "/src/root"}, tr.SourceDirs())
+}
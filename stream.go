@@ -0,0 +1,60 @@
+package depth
+
+import "context"
+
+// streamBufferSize is the number of packages buffered by ResolveStream, beyond which
+// resolution waits for them to be received.
+const streamBufferSize = 64
+
+// ResolveStream resolves the package named like Resolve, but sends each Pkg on the returned
+// channel once it has been imported, in breadth-first order. The channel is closed when
+// resolution is done, after which the result of Resolve is sent on the error channel.
+//
+// Each Pkg sent is a copy, safe to read while resolution continues. Its Deps are left
+// empty since they are still being resolved, and its Parent is a copy of the same kind.
+// Sorting and merging test dependencies only apply to the final tree. Dependencies beyond
+// MaxBreadth are limited as each level is resolved, so they're never sent, but the placeholder
+// noting how many were omitted is only added to the final tree.
+//
+// A limited number of packages are buffered, after which resolution waits for the consumer,
+// so the channel must be read until it's closed or the context is cancelled. Cancelling the
+// context stops resolution, leaving the rest of the tree unresolved, and the error of the
+// context is sent. The Tree must not be used until the error channel has yielded a result.
+func (t *Tree) ResolveStream(ctx context.Context, name string) (<-chan *Pkg, <-chan error) {
+	pkgs := make(chan *Pkg, streamBufferSize)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+
+		err := t.resolve(name, func(p *Pkg) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			select {
+			case pkgs <- p.snapshot():
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+		close(pkgs)
+
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		errs <- err
+	}()
+
+	return pkgs, errs
+}
+
+// snapshot returns a copy of the Pkg, and of each of its parents, without their Deps.
+func (p *Pkg) snapshot() *Pkg {
+	c := *p
+	c.Deps = nil
+	if p.Parent != nil {
+		c.Parent = p.Parent.snapshot()
+	}
+	return &c
+}
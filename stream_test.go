@@ -0,0 +1,73 @@
+package depth
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_ResolveStream(t *testing.T) {
+	tr := Tree{
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports := map[string][]string{
+				"root": {"a", "b"},
+				"a":    {"c"},
+				"b":    {"a"},
+			}
+			return &build.Package{ImportPath: name, Imports: imports[name]}, nil
+		}},
+	}
+	pkgs, errs := tr.ResolveStream(context.Background(), "root")
+
+	var paths []string
+	for p := range pkgs {
+		path := p.Name
+		for parent := p.Parent; parent != nil; parent = parent.Parent {
+			path = parent.Name + "/" + path
+		}
+		assert.Empty(t, p.Deps)
+		paths = append(paths, path)
+	}
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []string{"root", "root/a", "root/b", "root/a/c", "root/b/a"}, paths)
+
+	// The resolved tree is complete, and no longer streamed.
+	assert.Len(t, tr.Root.Deps, 2)
+	assert.NoError(t, tr.Resolve("root"))
+}
+
+func TestTree_ResolveStreamCancel(t *testing.T) {
+	var deps []string
+	for i := 0; i < 2*streamBufferSize; i++ {
+		deps = append(deps, fmt.Sprintf("dep%d", i))
+	}
+
+	// Packages are imported concurrently, so they're counted atomically.
+	var imports atomic.Int32
+	tr := Tree{
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imports.Add(1)
+			if name == "root" {
+				return &build.Package{ImportPath: name, Imports: deps}, nil
+			}
+			return &build.Package{ImportPath: name, Imports: []string{name + "/child"}}, nil
+		}},
+	}
+
+	// The consumer stops reading once the buffer fills, and cancels the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	pkgs, errs := tr.ResolveStream(ctx, "root")
+	<-pkgs
+	cancel()
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	for range pkgs {
+	}
+
+	// Resolution stops at the level being sent, without importing the children.
+	assert.LessOrEqual(t, imports.Load(), int32(1+len(deps)))
+}
@@ -0,0 +1,99 @@
+package depth
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// maxSVGNodes is the largest number of packages laid out by a Formatter returned by
+	// NewSVGFormatter.
+	maxSVGNodes = 150
+
+	svgCharWidth   = 7
+	svgNodePadding = 10
+	svgNodeHeight  = 24
+	svgNodeSpacing = 20
+	svgLayerHeight = 80
+	svgMargin      = 20
+)
+
+// svgNode is a package laid out by writeSVG.
+type svgNode struct {
+	name        string
+	internal    bool
+	x, y, width int
+}
+
+// NewSVGFormatter returns a Formatter writing the dependencies as an SVG image, with a node
+// for each unique package and a line for each unique import. The packages are laid out in
+// layers by the minimum depth at which they're imported, so it's only suited to small and
+// medium sized trees, and returns an error for larger ones.
+func NewSVGFormatter() Formatter {
+	return FormatterFunc(func(w io.Writer, root *Pkg) error {
+		// Placeholders of omitted dependencies would appear to be packages.
+		return writeSVG(w, root.WithoutOmitted())
+	})
+}
+
+func writeSVG(w io.Writer, root *Pkg) error {
+	var order []string
+	layers := make(map[string]int)
+	internal := make(map[string]bool)
+	root.Walk(func(p *Pkg, depth int) bool {
+		if d, ok := layers[p.Name]; !ok {
+			order = append(order, p.Name)
+			layers[p.Name] = depth
+			internal[p.Name] = p.Internal
+		} else if depth < d {
+			layers[p.Name] = depth
+		}
+		return true
+	})
+	if len(order) > maxSVGNodes {
+		return fmt.Errorf("%d packages are too many to lay out as SVG (the limit is %d), try -format dot instead", len(order), maxSVGNodes)
+	}
+
+	// Each layer is a row of packages, in the order they are first seen.
+	nodes := make(map[string]*svgNode, len(order))
+	rowWidths := make(map[int]int)
+	var width, height int
+	for _, name := range order {
+		layer := layers[name]
+		n := &svgNode{
+			name:     name,
+			internal: internal[name],
+			x:        svgMargin + rowWidths[layer],
+			y:        svgMargin + layer*svgLayerHeight,
+			width:    len(name)*svgCharWidth + 2*svgNodePadding,
+		}
+		nodes[name] = n
+		rowWidths[layer] += n.width + svgNodeSpacing
+		width = max(width, n.x+n.width+svgMargin)
+		height = max(height, n.y+svgNodeHeight+svgMargin)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n", width, height)
+	for from, to := range root.Edges {
+		f, t := nodes[from.Name], nodes[to.Name]
+		fmt.Fprintf(&b, `  <line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n",
+			f.x+f.width/2, f.y+svgNodeHeight, t.x+t.width/2, t.y)
+	}
+	for _, name := range order {
+		n := nodes[name]
+		fill := "#fff"
+		if n.internal {
+			fill = "#eee"
+		}
+		fmt.Fprintf(&b, `  <rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#333"/>`+"\n",
+			n.x, n.y, n.width, svgNodeHeight, fill)
+		fmt.Fprintf(&b, `  <text x="%d" y="%d">%s</text>`+"\n",
+			n.x+svgNodePadding, n.y+svgNodeHeight/2+4, xmlEscape(n.name))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,43 @@
+package depth
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSVGFormatterLimit(t *testing.T) {
+	p := Pkg{Name: "root"}
+	for i := 0; i < maxSVGNodes; i++ {
+		p.Deps = append(p.Deps, Pkg{Name: fmt.Sprintf("pkg%d", i)})
+	}
+
+	err := NewSVGFormatter().Format(io.Discard, &p)
+	assert.EqualError(t, err, "151 packages are too many to lay out as SVG (the limit is 150), try -format dot instead")
+}
+
+func ExampleNewSVGFormatter() {
+	p := Pkg{Name: "root", Deps: []Pkg{
+		{Name: "strings", Internal: true},
+		{Name: "github.com/foo/bar", Deps: []Pkg{
+			{Name: "strings", Internal: true},
+		}},
+	}}
+
+	_ = NewSVGFormatter().Format(os.Stdout, &p)
+	// Output:
+	// <svg xmlns="http://www.w3.org/2000/svg" width="275" height="144" font-family="monospace" font-size="12">
+	//   <line x1="44" y1="44" x2="54" y2="100" stroke="#999"/>
+	//   <line x1="44" y1="44" x2="182" y2="100" stroke="#999"/>
+	//   <line x1="182" y1="124" x2="54" y2="100" stroke="#999"/>
+	//   <rect x="20" y="20" width="48" height="24" fill="#fff" stroke="#333"/>
+	//   <text x="30" y="36">root</text>
+	//   <rect x="20" y="100" width="69" height="24" fill="#eee" stroke="#333"/>
+	//   <text x="30" y="116">strings</text>
+	//   <rect x="109" y="100" width="146" height="24" fill="#fff" stroke="#333"/>
+	//   <text x="119" y="116">github.com/foo/bar</text>
+	// </svg>
+}
@@ -0,0 +1,6 @@
+// Package buildtags is a fixture with an import that is only included by a build tag.
+package buildtags
+
+import "strings"
+
+var _ = strings.ToUpper
@@ -0,0 +1,7 @@
+//go:build sometag
+
+package buildtags
+
+import "net/url"
+
+var _ = url.Parse
@@ -0,0 +1,6 @@
+// Package cgo is a fixture with a file using cgo, which is only included when cgo is enabled.
+package cgo
+
+import "strings"
+
+var _ = strings.ToUpper
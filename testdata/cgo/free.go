@@ -0,0 +1,8 @@
+package cgo
+
+// #include <stdlib.h>
+import "C"
+
+import "unsafe"
+
+var _ = func(p unsafe.Pointer) { C.free(p) }
@@ -0,0 +1,6 @@
+package exported
+
+import "strings"
+
+// Builder builds strings.
+type Builder = strings.Builder
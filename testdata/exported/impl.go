@@ -0,0 +1,9 @@
+package exported
+
+import "bytes"
+
+type impl struct{}
+
+func (impl) Len(b *bytes.Buffer) int {
+	return b.Len()
+}
@@ -0,0 +1,5 @@
+package strings
+
+import "errors"
+
+var ErrFake = errors.New("fake")
@@ -0,0 +1,5 @@
+package loc
+
+import "strings"
+
+var Upper = strings.ToUpper
@@ -0,0 +1,5 @@
+package bar
+
+import "fmt"
+
+var _ = fmt.Sprint
@@ -0,0 +1,6 @@
+// Package foo is a fixture of a directory containing packages named unlike the directory.
+package foo
+
+import "strings"
+
+var _ = strings.ToUpper
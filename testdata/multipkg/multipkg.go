@@ -0,0 +1,6 @@
+// Package multipkg is a fixture of a directory that also contains a file of another package.
+package multipkg
+
+import "strings"
+
+var _ = strings.ToUpper
@@ -0,0 +1,5 @@
+package other
+
+import "fmt"
+
+var _ = fmt.Sprint
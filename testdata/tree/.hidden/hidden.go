@@ -0,0 +1,5 @@
+package hidden
+
+import "fmt"
+
+var Print = fmt.Println
@@ -0,0 +1,5 @@
+package a
+
+import "strings"
+
+var Upper = strings.ToUpper
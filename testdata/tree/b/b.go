@@ -0,0 +1,8 @@
+package b
+
+import (
+	"errors"
+	"strings"
+)
+
+var Err = errors.New(strings.Repeat("b", 2))
@@ -0,0 +1,5 @@
+package c
+
+import "github.com/adapap/depth/testdata/tree/a"
+
+var Upper = a.Upper
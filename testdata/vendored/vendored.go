@@ -0,0 +1,5 @@
+package vendored
+
+import "example.com/lib"
+
+var Upper = lib.Upper
@@ -0,0 +1,143 @@
+package depth
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"sync"
+)
+
+// TreeCache caches the trees resolved for each package, so that resolving a package again
+// with the same settings copies the earlier tree rather than resolving it from scratch. This
+// suits a long-running process that resolves the same packages many times. It's safe for
+// concurrent use, and its zero value is ready to use.
+//
+// Trees are keyed by the name of the package and the settings of the Tree that change how it
+// is resolved, such as its MaxDepth and IncludePatterns, so changing any of them resolves the
+// package again. Since functions can't be compared, the InternalFunc, ResolveInternalFunc and
+// Importer of the Tree are not part of the key. Nothing is invalidated when files change,
+// which is left to Invalidate and Clear.
+type TreeCache struct {
+	mu    sync.Mutex
+	trees map[treeCacheKey]*Pkg
+}
+
+// treeCacheKey identifies a resolved tree within a TreeCache. Relative packages are also
+// identified by the working directory they were resolved from. The module is the main module
+// the tree is resolved within, whether set as the ModulePrefix or detected.
+type treeCacheKey struct {
+	name     string
+	dir      string
+	module   string
+	settings string
+}
+
+// Resolve resolves the package named on the Tree, as Tree.Resolve does, unless it was already
+// resolved by the TreeCache with the same settings. In that case, the Root of the Tree is set
+// to a copy of the cached tree, though the rest of the state of the Tree, such as the modules
+// seen while resolving, is left empty. Only trees resolved without error are cached.
+func (c *TreeCache) Resolve(t *Tree, name string) error {
+	key, err := newTreeCacheKey(t, name)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.trees[key]
+	c.mu.Unlock()
+	if ok {
+		t.resetState()
+		t.mainModule = key.module
+		t.Root = cached.clone(t)
+		return nil
+	}
+
+	if err := t.Resolve(name); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trees == nil {
+		c.trees = make(map[treeCacheKey]*Pkg)
+	}
+	c.trees[key] = t.Root.clone(nil)
+	return nil
+}
+
+// Invalidate removes the trees of the package named from the cache, for every setting it
+// was resolved with.
+func (c *TreeCache) Invalidate(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.trees {
+		if key.name == name {
+			delete(c.trees, key)
+		}
+	}
+}
+
+// Clear removes every tree from the cache.
+func (c *TreeCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.trees = nil
+}
+
+// Len returns the number of trees in the cache.
+func (c *TreeCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.trees)
+}
+
+// newTreeCacheKey returns the key of the tree of the package named, resolved with the settings
+// of the Tree provided.
+func newTreeCacheKey(t *Tree, name string) (treeCacheKey, error) {
+	key := treeCacheKey{name: name}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return key, err
+	}
+	if build.IsLocalImport(name) {
+		key.dir = pwd
+	}
+	key.module = t.modulePrefix(pwd)
+
+	var cgo string
+	if t.CgoEnabled != nil {
+		cgo = fmt.Sprint(*t.CgoEnabled)
+	}
+	key.settings = fmt.Sprintf("%#v", []any{
+		t.ResolveInternal, t.ResolveTest, t.ResolveXTest, t.MaxDepth,
+		t.IncludePatterns, t.ExcludePatterns, t.MergeTestDeps, t.SeparateTestRoots,
+		t.HideInternalNoise, t.PruneNoise, t.NoisePackages, cgo, t.BuildTags, t.GOROOT, t.ModuleMode,
+		t.NoFollowSymlinks, t.Timeout, t.FindOnly, t.StopAtExternal, t.OpaquePackages,
+		t.CountLOC, t.IgnoreVendor, t.SortMode, t.MaxBreadth, t.MaxPackages,
+		t.VendorDir,
+	})
+	return key, nil
+}
+
+// clone returns a deep copy of the Pkg and its dependencies, belonging to the Tree provided.
+// The Raw packages are shared, since they are never modified.
+func (p *Pkg) clone(t *Tree) *Pkg {
+	var c Pkg
+	p.cloneInto(&c, t, nil)
+	return &c
+}
+
+func (p *Pkg) cloneInto(c *Pkg, t *Tree, parent *Pkg) {
+	*c = *p
+	c.Tree, c.Parent = t, parent
+	c.Deps = nil
+	if p.Deps != nil {
+		c.Deps = make([]Pkg, len(p.Deps))
+	}
+	for i := range p.Deps {
+		p.Deps[i].cloneInto(&c.Deps[i], t, c)
+	}
+}
@@ -0,0 +1,92 @@
+package depth
+
+import (
+	"errors"
+	"go/build"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTreeCache_Resolve(t *testing.T) {
+	// Packages are imported concurrently, so they're counted atomically.
+	var imports atomic.Int32
+	importer := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		imports.Add(1)
+		deps := map[string][]string{"root": {"a", "b"}, "a": {"c"}}
+		return &build.Package{ImportPath: name, Imports: deps[name]}, nil
+	}}
+
+	var c TreeCache
+	tr := Tree{Importer: importer}
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.Equal(t, int32(4), imports.Load())
+	assert.Equal(t, 1, c.Len())
+
+	// A hit copies the cached tree without importing anything.
+	cached := Tree{Importer: importer}
+	assert.NoError(t, c.Resolve(&cached, "root"))
+	assert.Equal(t, int32(4), imports.Load())
+	assert.Equal(t, tr.Stats(), cached.Stats())
+	assert.Same(t, &cached, cached.Root.Deps[0].Tree)
+	assert.Same(t, cached.Root, cached.Root.Deps[0].Parent)
+	assert.Same(t, &cached.Root.Deps[0], cached.Root.Deps[0].Deps[0].Parent)
+
+	// Modifying the copy leaves the cache untouched.
+	cached.Root.Deps = nil
+	assert.NoError(t, c.Resolve(&cached, "root"))
+	assert.Len(t, cached.Root.Deps, 2)
+
+	// Different settings resolve the package again.
+	limited := Tree{Importer: importer, MaxDepth: 1}
+	assert.NoError(t, c.Resolve(&limited, "root"))
+	assert.Equal(t, int32(7), imports.Load())
+	assert.Equal(t, 2, c.Len())
+
+	c.Invalidate("root")
+	assert.Equal(t, 0, c.Len())
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.Equal(t, int32(11), imports.Load())
+
+	c.Clear()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestTreeCache_ResolveReusedTree(t *testing.T) {
+	var imports atomic.Int32
+	importer := MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		imports.Add(1)
+		return &build.Package{ImportPath: name, Imports: map[string][]string{"root": {"a"}}[name]}, nil
+	}}
+
+	// Resolving the same Tree again hits the cache, despite its main module being detected.
+	var c TreeCache
+	tr := Tree{Importer: importer}
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.Equal(t, int32(2), imports.Load())
+	assert.Equal(t, 1, c.Len())
+	assert.Equal(t, "github.com/adapap/depth", tr.MainModule())
+
+	// An explicit ModulePrefix naming the same module shares the tree.
+	tr.ModulePrefix = "github.com/adapap/depth"
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.Equal(t, 1, c.Len())
+
+	// A different main module resolves the package again.
+	tr.ModulePrefix = "github.com/foo/bar"
+	assert.NoError(t, c.Resolve(&tr, "root"))
+	assert.Equal(t, int32(4), imports.Load())
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestTreeCache_ResolveError(t *testing.T) {
+	var c TreeCache
+	tr := Tree{Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+		return nil, errors.New("not found")
+	}}}
+
+	assert.ErrorIs(t, c.Resolve(&tr, "root"), ErrRootPkgNotResolved)
+	assert.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,55 @@
+package depth
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+)
+
+// VendorImporter is an Importer that finds packages within a vendor directory before
+// delegating to another Importer, so that the packages a vendored build would compile are
+// resolved rather than those implied by the module graph.
+type VendorImporter struct {
+	// Importer imports the packages requested. If nil, build.Default is used.
+	Importer Importer
+	// Dir is the vendor directory, within which the package at each import path is found in
+	// the directory of the same path.
+	Dir string
+}
+
+// NewVendorImporter returns a VendorImporter finding packages within the vendor directory
+// provided before delegating to the Importer provided.
+func NewVendorImporter(i Importer, dir string) *VendorImporter {
+	return &VendorImporter{Importer: i, Dir: dir}
+}
+
+// Import imports the package at the path provided from the vendor directory if it's found
+// there, and from the Importer otherwise. Vendored packages are imported by their directory
+// through the Importer, keeping the path they were requested by.
+func (v *VendorImporter) Import(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+	var importer Importer = &build.Default
+	if v.Importer != nil {
+		importer = v.Importer
+	}
+
+	if v.Dir == "" || build.IsLocalImport(name) || filepath.IsAbs(name) {
+		return importer.Import(name, srcDir, im)
+	}
+
+	dir, err := filepath.Abs(filepath.Join(v.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		return importer.Import(name, srcDir, im)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return importer.Import(name, srcDir, im)
+	}
+
+	// The package is copied, since a caching Importer may share it.
+	pkg, err := importer.Import(".", dir, im)
+	if pkg == nil {
+		return nil, err
+	}
+	c := *pkg
+	c.ImportPath = name
+	return &c, err
+}
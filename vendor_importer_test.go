@@ -0,0 +1,49 @@
+package depth
+
+import (
+	"go/build"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVendorImporter(t *testing.T) {
+	var imported []string
+	v := VendorImporter{
+		Dir: "testdata/vendored/vendor",
+		Importer: MockImporter{ImportFn: func(name, srcDir string, im build.ImportMode) (*build.Package, error) {
+			imported = append(imported, name+" "+filepath.ToSlash(srcDir))
+			return &build.Package{ImportPath: name, Dir: srcDir}, nil
+		}},
+	}
+
+	pkg, err := v.Import("example.com/lib", "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/lib", pkg.ImportPath)
+	dir, _ := filepath.Abs("testdata/vendored/vendor/example.com/lib")
+	assert.Equal(t, dir, pkg.Dir)
+
+	pkg, err = v.Import("example.com/missing", "src", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/missing", pkg.ImportPath)
+	assert.Equal(t, []string{". " + filepath.ToSlash(dir), "example.com/missing src"}, imported)
+}
+
+func TestTree_ResolveVendorDir(t *testing.T) {
+	tr := Tree{VendorDir: "testdata/vendored/vendor"}
+	assert.NoError(t, tr.Resolve("./testdata/vendored"))
+
+	lib := tr.Root.Find("example.com/lib")
+	if assert.NotNil(t, lib) {
+		assert.True(t, lib.Resolved)
+		assert.False(t, lib.Internal)
+		assert.Equal(t, "example.com/lib", lib.Raw.ImportPath)
+	}
+
+	tr = Tree{}
+	assert.NoError(t, tr.Resolve("./testdata/vendored"))
+	if lib := tr.Root.Find("example.com/lib"); assert.NotNil(t, lib) {
+		assert.False(t, lib.Resolved)
+	}
+}